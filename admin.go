@@ -0,0 +1,307 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package out
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// adminHandler is the http.Handler returned by AdminHandler()
+type adminHandler struct {
+	mu      sync.Mutex
+	reverts map[string]*adminRevert // pending auto-reverts by setting
+}
+
+// adminRevert is a change made via the admin handler that will be undone
+// when its TTL expires
+type adminRevert struct {
+	timer *time.Timer
+	at    time.Time
+	undo  func()
+}
+
+// adminStatus is the JSON form of what the admin handler reports
+type adminStatus struct {
+	State   *State               `json:"state"`
+	Counts  map[string]uint64    `json:"counts"`
+	Reverts map[string]time.Time `json:"reverts,omitempty"`
+}
+
+// AdminHandler returns an http.Handler to look at and change the 'out'
+// settings of a running program, typically mounted on a local debug mux or
+// a unix socket (it has no access control of its own), eg:
+//
+//	mux.Handle("/out/", out.AdminHandler())
+//
+// The last element of the request path selects what to do:
+//
+//	GET /out/                              the settings and message counts
+//	PUT /out/threshold?target=logfile&level=debug
+//	PUT /out/flags?target=screen&level=all&flags=time,shortfile
+//	PUT /out/stacktrace?config=both,allissues
+//	PUT /out/scope?scope=github.com/me/tool/pkg
+//
+// Targets are "screen", "logfile" or "both" and levels and flags use the
+// names of the config file (see LoadConfig()).  Any PUT may add a TTL, eg:
+// "&ttl=10m", after which the setting reverts to its value from before the
+// change (so debugging turns itself off).  The settings and counts are
+// returned as JSON (see Snapshot() and Count()), errors as plain text.
+func AdminHandler() http.Handler {
+	return &adminHandler{reverts: make(map[string]*adminRevert)}
+}
+
+// ServeHTTP dispatches the admin request, see AdminHandler()
+func (h *adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	setting := path.Base(r.URL.Path)
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		switch setting {
+		case "/", ".", "out", "state":
+			h.writeStatus(w)
+		default:
+			http.Error(w, fmt.Sprintf("unknown 'out' admin request %q", r.URL.Path), http.StatusNotFound)
+		}
+		return
+	}
+	if r.Method != http.MethodPut && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	var ttl time.Duration
+	if ttlStr := q.Get("ttl"); ttlStr != "" {
+		var err error
+		ttl, err = time.ParseDuration(ttlStr)
+		if err != nil || ttl <= 0 {
+			http.Error(w, fmt.Sprintf("invalid ttl %q, expected a positive duration (eg: 10m)", ttlStr), http.StatusBadRequest)
+			return
+		}
+	}
+	var changes []adminChange
+	var err error
+	switch setting {
+	case "threshold":
+		changes, err = thresholdChanges(q.Get("target"), q.Get("level"))
+	case "flags":
+		changes, err = flagsChanges(q.Get("target"), q.Get("level"), q.Get("flags"))
+	case "stacktrace":
+		changes, err = stackTraceChanges(q.Get("config"))
+	case "scope":
+		changes = scopeChanges(q.Get("scope"))
+	default:
+		http.Error(w, fmt.Sprintf("unknown 'out' admin request %q", r.URL.Path), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, change := range changes {
+		h.apply(change, ttl)
+	}
+	h.writeStatus(w)
+}
+
+// adminChange is a single setting change, the undo func is captured before
+// the change is applied so it can be reverted later
+type adminChange struct {
+	key   string // setting name (eg: "threshold.screen")
+	desc  string // what the change does, for the Note logged
+	undo  func() // restores the current value
+	apply func() // makes the change
+}
+
+// apply makes the change, if a TTL is given it's undone after the TTL, a
+// pending revert of the same setting keeps its (original) undo and just has
+// its timer reset, a change without a TTL cancels any pending revert
+func (h *adminHandler) apply(change adminChange, ttl time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	pending := h.reverts[change.key]
+	if pending != nil {
+		pending.timer.Stop()
+		delete(h.reverts, change.key)
+	}
+	change.apply()
+	if ttl == 0 {
+		Notef("Admin request: %s\n", change.desc)
+		return
+	}
+	Notef("Admin request: %s (reverts in %s)\n", change.desc, ttl)
+	revert := &adminRevert{at: now().Add(ttl), undo: change.undo}
+	if pending != nil {
+		revert.undo = pending.undo
+	}
+	revert.timer = time.AfterFunc(ttl, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if h.reverts[change.key] != revert {
+			return // cancelled or replaced
+		}
+		delete(h.reverts, change.key)
+		revert.undo()
+		Notef("Admin request TTL expired, reverted %s\n", change.key)
+	})
+	h.reverts[change.key] = revert
+}
+
+// writeStatus writes the current settings, counts and pending reverts
+func (h *adminHandler) writeStatus(w http.ResponseWriter) {
+	status := adminStatus{State: Snapshot(), Counts: make(map[string]uint64)}
+	for level := LevelTrace; level < LevelDiscard; level++ {
+		status.Counts[level.String()] = Count(level)
+	}
+	h.mu.Lock()
+	if len(h.reverts) != 0 {
+		status.Reverts = make(map[string]time.Time)
+		for key, pending := range h.reverts {
+			status.Reverts[key] = pending.at
+		}
+	}
+	h.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(status)
+}
+
+// parseTargets maps "screen", "logfile" or "both" to the output targets
+func parseTargets(target string) ([]int, error) {
+	switch strings.ToLower(target) {
+	case "screen":
+		return []int{ForScreen}, nil
+	case "logfile":
+		return []int{ForLogfile}, nil
+	case "both":
+		return []int{ForScreen, ForLogfile}, nil
+	}
+	return nil, fmt.Errorf("invalid target %q (valid: screen, logfile, both)", target)
+}
+
+// targetName is the name of a single output target
+func targetName(outputTgt int) string {
+	if outputTgt == ForScreen {
+		return "screen"
+	}
+	return "logfile"
+}
+
+// thresholdChanges builds the changes to set the threshold of the target(s)
+func thresholdChanges(target, levelName string) ([]adminChange, error) {
+	targets, err := parseTargets(target)
+	if err != nil {
+		return nil, err
+	}
+	level, err := ParseLevel(levelName)
+	if err != nil {
+		return nil, err
+	}
+	var changes []adminChange
+	for _, tgt := range targets {
+		tgt := tgt
+		old := Threshold(tgt)
+		changes = append(changes, adminChange{
+			key:   "threshold." + targetName(tgt),
+			desc:  fmt.Sprintf("%s threshold %s -> %s", targetName(tgt), old, level),
+			undo:  func() { SetThreshold(old, tgt) },
+			apply: func() { SetThreshold(level, tgt) },
+		})
+	}
+	return changes, nil
+}
+
+// flagsChanges builds the changes to set the flags of a level ("all" for
+// every level) for the target(s)
+func flagsChanges(target, levelName, flagStr string) ([]adminChange, error) {
+	targets, err := parseTargets(target)
+	if err != nil {
+		return nil, err
+	}
+	levels := []Level{}
+	if strings.ToLower(levelName) == "all" {
+		for level := LevelTrace; level < LevelDiscard; level++ {
+			levels = append(levels, level)
+		}
+		levelName = "ALL"
+	} else {
+		level, err := ParseLevel(levelName)
+		if err != nil {
+			return nil, err
+		}
+		if level == LevelDiscard {
+			return nil, fmt.Errorf("level %q has no output settings", levelName)
+		}
+		levels = append(levels, level)
+		levelName = level.String()
+	}
+	flags, err := parseFlags(flagStr)
+	if err != nil {
+		return nil, err
+	}
+	var changes []adminChange
+	for _, tgt := range targets {
+		tgt := tgt
+		oldFlags := make(map[Level]int)
+		for _, level := range levels {
+			oldFlags[level] = Flags(level, tgt)
+		}
+		changes = append(changes, adminChange{
+			key:  fmt.Sprintf("flags.%s.%s", levelName, targetName(tgt)),
+			desc: fmt.Sprintf("%s %s flags -> %q", levelName, targetName(tgt), strings.Join(flagNames(flags), ",")),
+			undo: func() {
+				for level, old := range oldFlags {
+					SetFlags(level, old, tgt)
+				}
+			},
+			apply: func() {
+				for _, level := range levels {
+					SetFlags(level, flags, tgt)
+				}
+			},
+		})
+	}
+	return changes, nil
+}
+
+// stackTraceChanges builds the change to set the stack trace config
+func stackTraceChanges(cfgStr string) ([]adminChange, error) {
+	cfg, err := parseStackTraceConfig(cfgStr)
+	if err != nil {
+		return nil, err
+	}
+	old := StackTraceConfig()
+	return []adminChange{{
+		key:   "stacktrace",
+		desc:  fmt.Sprintf("stack trace config %s -> %s", stackTraceConfigString(old), stackTraceConfigString(cfg)),
+		undo:  func() { SetStackTraceConfig(old) },
+		apply: func() { SetStackTraceConfig(cfg) },
+	}}, nil
+}
+
+// scopeChanges builds the change to set the debug scope ("" for none)
+func scopeChanges(scope string) []adminChange {
+	old := DebugScope()
+	return []adminChange{{
+		key:   "scope",
+		desc:  fmt.Sprintf("debug scope %q -> %q", old, scope),
+		undo:  func() { SetDebugScope(old) },
+		apply: func() { SetDebugScope(scope) },
+	}}
+}
@@ -0,0 +1,114 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package test for: out/admin.go
+//   Testing in this file focuses on looking at and changing the settings via
+//   the admin http handler, including TTL based reverts
+
+package out
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dvln/testify/assert"
+)
+
+// adminRequest runs a request through the admin handler
+func adminRequest(h http.Handler, method, url string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, url, nil))
+	return rec
+}
+
+func TestAdminHandler(t *testing.T) {
+	screenBuf := &syncBuffer{}
+	SetWriter(LevelAll, screenBuf, ForScreen)
+	h := AdminHandler()
+
+	notes := Count(LevelNote)
+	Noteln("counted")
+	resp := adminRequest(h, "GET", "/out/")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+	var status struct {
+		State  map[string]interface{} `json:"state"`
+		Counts map[string]uint64      `json:"counts"`
+	}
+	assert.Equal(t, nil, json.Unmarshal(resp.Body.Bytes(), &status))
+	assert.Equal(t, "INFO", status.State["screenThreshold"])
+	assert.Equal(t, notes+1, status.Counts["NOTE"])
+
+	resp = adminRequest(h, "PUT", "/out/threshold?target=logfile&level=debug")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"logfileThreshold": "DEBUG"`)
+	assert.Equal(t, LevelDebug, Threshold(ForLogfile))
+	assert.Contains(t, screenBuf.String(), "Note: Admin request: logfile threshold DISCARD -> DEBUG\n")
+
+	resp = adminRequest(h, "PUT", "/out/flags?target=both&level=note&flags=time,shortfile")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, Ltime|Lshortfile, Flags(LevelNote, ForScreen))
+	assert.Equal(t, Ltime|Lshortfile, Flags(LevelNote, ForLogfile))
+	assert.Equal(t, 0, Flags(LevelInfo, ForScreen))
+
+	resp = adminRequest(h, "PUT", "/out/stacktrace?config=both,allissues")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, ForBoth|StackTraceAllIssues, StackTraceConfig())
+
+	resp = adminRequest(h, "PUT", "/out/scope?scope=github.com/dvln/out")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "github.com/dvln/out", DebugScope())
+
+	// bad requests change nothing
+	resp = adminRequest(h, "PUT", "/out/threshold?target=screen&level=loud")
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), `unknown level "loud"`)
+	resp = adminRequest(h, "PUT", "/out/threshold?target=printer&level=debug")
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	resp = adminRequest(h, "PUT", "/out/flags?target=screen&level=all&flags=tiem")
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	resp = adminRequest(h, "PUT", "/out/threshold?target=screen&level=debug&ttl=soon")
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	resp = adminRequest(h, "PUT", "/out/volume?level=11")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	resp = adminRequest(h, "DELETE", "/out/threshold")
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.Code)
+	assert.Equal(t, LevelInfo, Threshold(ForScreen))
+	ResetOutPkg()
+}
+
+func TestAdminHandlerTTL(t *testing.T) {
+	screenBuf := &syncBuffer{}
+	SetWriter(LevelAll, screenBuf, ForScreen)
+	h := AdminHandler()
+
+	resp := adminRequest(h, "PUT", "/out/threshold?target=screen&level=trace&ttl=1h")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"threshold.screen": "`)
+	// a second change keeps the original value to revert to
+	adminRequest(h, "PUT", "/out/threshold?target=screen&level=debug&ttl=50ms")
+	assert.Equal(t, LevelDebug, Threshold(ForScreen))
+	assert.Equal(t, true, waitFor(func() bool { return Threshold(ForScreen) == LevelInfo }))
+	assert.NotContains(t, adminRequest(h, "GET", "/out/state").Body.String(), `"reverts"`)
+	assert.Contains(t, screenBuf.String(), "Admin request TTL expired, reverted threshold.screen\n")
+
+	// a change without a TTL cancels a pending revert
+	adminRequest(h, "PUT", "/out/scope?scope=mypkg&ttl=50ms")
+	adminRequest(h, "PUT", "/out/scope?scope=otherpkg")
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, "otherpkg", DebugScope())
+	ResetOutPkg()
+}
@@ -0,0 +1,198 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package out
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// These are the color configs for screen output, see SetColorConfig()
+const (
+	ColorAuto   = iota // Color if the screen writer is a terminal (default)
+	ColorAlways        // Always color screen output (eg: a --color=always opt)
+	ColorNever         // Never color screen output
+)
+
+// colorConfig is the color config for screen output, see SetColorConfig()
+var colorConfig = ColorAuto
+
+// ColorConfig returns the current color config, see SetColorConfig()
+func ColorConfig() int {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return colorConfig
+}
+
+// SetColorConfig sets if the level prefixes and flag metadata (eg: the time
+// stamp) of screen output are colored, the colors used are set per level via
+// SetLevelColors().  The config is one of:
+//
+//	ColorAuto   // color if the screen writer is a terminal (the default)
+//	ColorAlways // always color
+//	ColorNever  // never color
+//
+// In auto mode the NO_COLOR env var (if set to anything) turns color off, then
+// FORCE_COLOR (if set, other than to "0" or "false") turns it on and then
+// TERM=dumb turns it off.  Log file output is never colored.
+func SetColorConfig(cfg int) {
+	mutex.Lock()
+	{
+		colorConfig = cfg
+	}
+	mutex.Unlock()
+}
+
+// parseColorConfig maps "auto", "always" or "never" to the color config
+func parseColorConfig(str string) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(str)) {
+	case "auto", "":
+		return ColorAuto, nil
+	case "always", "on":
+		return ColorAlways, nil
+	case "never", "off":
+		return ColorNever, nil
+	}
+	return ColorAuto, fmt.Errorf("invalid color config %q (valid: auto, always, never)", str)
+}
+
+// LevelColors returns the colors of the prefix and of the flag metadata of
+// the given level, see SetLevelColors()
+func LevelColors(level Level) (string, string) {
+	level = levelCheck(level)
+	var prefixColor, metaColor string
+	for _, o := range outputters {
+		o.mu.RLock()
+		if o.level == level {
+			prefixColor, metaColor = o.prefixColor, o.metaColor
+		}
+		o.mu.RUnlock()
+	}
+	return prefixColor, metaColor
+}
+
+// SetLevelColors sets the colors of the prefix (eg: "Error: ") and the flag
+// metadata (eg: the time stamp) of the given level (or LevelAll) for screen
+// output.  Colors are ANSI SGR parameters, eg: "31" is red, "1;31" is bold red
+// and "90" is gray, use "" for no color.  The defaults are gray metadata with
+// cyan debug, green note, yellow issue, red error and bold red fatal prefixes.
+func SetLevelColors(level Level, prefixColor string, metaColor string) {
+	for _, o := range outputters {
+		o.mu.Lock()
+		if level == LevelAll || o.level == level {
+			o.prefixColor = prefixColor
+			o.metaColor = metaColor
+		}
+		o.mu.Unlock()
+	}
+}
+
+// screenColor returns true if screen output for this level should be colored
+// based on the color config, the env and the screen writer
+func (o *LvlOutput) screenColor() bool {
+	o.mu.RLock()
+	w := o.screenHndl
+	o.mu.RUnlock()
+	return colorWanted(ColorConfig(), w)
+}
+
+// colorWanted decides if output to the given writer should be colored, see
+// SetColorConfig() for how the config and env settings are used
+func colorWanted(cfg int, w io.Writer) bool {
+	switch cfg {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if force := os.Getenv("FORCE_COLOR"); force != "" {
+		return force != "0" && force != "false"
+	}
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isTerminal(w)
+}
+
+var (
+	// terminals caches the isTerminal() result per file so screen output and
+	// status redraws don't stat the file every time, it's cleared wherever the
+	// screen writers are replaced (see resetTerminals())
+	terminals   = make(map[*os.File]bool)
+	terminalsMu sync.Mutex
+)
+
+// isTerminal returns true if the writer is a terminal (character device)
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	terminalsMu.Lock()
+	defer terminalsMu.Unlock()
+	if tty, ok := terminals[file]; ok {
+		return tty
+	}
+	info, err := file.Stat()
+	tty := err == nil && info.Mode()&os.ModeCharDevice != 0
+	terminals[file] = tty
+	return tty
+}
+
+// resetTerminals clears the isTerminal() cache, eg: when the writers change
+// (a new file may reuse the address of a closed one)
+func resetTerminals() {
+	terminalsMu.Lock()
+	terminals = make(map[*os.File]bool)
+	terminalsMu.Unlock()
+}
+
+// colorize wraps the string in the given color, any trailing spaces are left
+// outside of the color (so a colored prefix doesn't color the gap after it)
+func colorize(s string, color string) string {
+	if color == "" || s == "" {
+		return s
+	}
+	text := strings.TrimRight(s, " ")
+	if text == "" {
+		return s
+	}
+	return fmt.Sprintf("\x1b[%sm%s\x1b[0m%s", color, text, s[len(text):])
+}
+
+// stripANSI removes any ANSI escape sequences (eg: colors) from the string
+func stripANSI(s string) string {
+	if !strings.Contains(s, "\x1b[") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == 0x1b && i+1 < len(s) && s[i+1] == '[' {
+			// skip the parameters up to the final byte (0x40-0x7e)
+			i += 2
+			for i < len(s) && (s[i] < 0x40 || s[i] > 0x7e) {
+				i++
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
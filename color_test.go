@@ -0,0 +1,181 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package test for: out/color.go
+//   Testing in this file focuses on coloring the screen output, when color
+//   is used (config, env and terminal detection) and that the log file is
+//   never colored
+
+package out
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/dvln/testify/assert"
+)
+
+// setColorEnv sets (or unsets if "") the color related env settings and
+// returns a func to put back the original settings
+func setColorEnv(noColor, forceColor, term string) func() {
+	names := []string{"NO_COLOR", "FORCE_COLOR", "TERM"}
+	saved := make(map[string]*string)
+	for i, val := range []string{noColor, forceColor, term} {
+		if old, ok := os.LookupEnv(names[i]); ok {
+			saved[names[i]] = &old
+		} else {
+			saved[names[i]] = nil
+		}
+		if val == "" {
+			os.Unsetenv(names[i])
+		} else {
+			os.Setenv(names[i], val)
+		}
+	}
+	return func() {
+		for name, old := range saved {
+			if old == nil {
+				os.Unsetenv(name)
+			} else {
+				os.Setenv(name, *old)
+			}
+		}
+	}
+}
+
+func TestColorOutput(t *testing.T) {
+	restoreEnv := setColorEnv("", "", "")
+	defer restoreEnv()
+	screenBuf := new(bytes.Buffer)
+	logBuf := new(bytes.Buffer)
+	SetWriter(LevelAll, screenBuf, ForScreen)
+	SetWriter(LevelAll, logBuf, ForLogfile)
+	SetThreshold(LevelInfo, ForLogfile)
+	SetFlags(LevelAll, 0, ForLogfile)
+
+	// a buffer isn't a terminal so auto mode doesn't color
+	Issueln("plain")
+	assert.Equal(t, "Issue: plain\n", screenBuf.String())
+
+	// always colors the screen, the log file stays plain
+	screenBuf.Reset()
+	logBuf.Reset()
+	SetColorConfig(ColorAlways)
+	Issueln("yellow")
+	assert.Equal(t, "\x1b[33mIssue:\x1b[0m yellow\n", screenBuf.String())
+	assert.Equal(t, "Issue: yellow\n", logBuf.String())
+	assert.NotContains(t, logBuf.String(), "\x1b[")
+
+	// every line gets the colored prefix, blank prefixes use the visible width
+	screenBuf.Reset()
+	Issueln("line one\nline two")
+	assert.Equal(t, "\x1b[33mIssue:\x1b[0m line one\n\x1b[33mIssue:\x1b[0m line two\n", screenBuf.String())
+	assert.Equal(t, "       line two", InsertPrefix("line two", colorize("Issue: ", "33"), BlankInsert, 0))
+
+	// flag metadata is colored too, levels without a prefix color have none
+	screenBuf.Reset()
+	SetFlags(LevelInfo, Llevel, ForScreen)
+	Println("meta")
+	assert.Equal(t, "\x1b[90mINFO\x1b[0m    meta\n", screenBuf.String())
+	screenBuf.Reset()
+	SetLevelColors(LevelAll, "", "")
+	Issueln("no colors")
+	Println("none")
+	assert.Equal(t, "Issue: no colors\nINFO    none\n", screenBuf.String())
+	prefixColor, metaColor := LevelColors(LevelIssue)
+	assert.Equal(t, "", prefixColor)
+	assert.Equal(t, "", metaColor)
+	ResetOutPkg()
+	prefixColor, _ = LevelColors(LevelError)
+	assert.Equal(t, "31", prefixColor)
+	ResetOutPkg()
+}
+
+func TestColorWanted(t *testing.T) {
+	defer setColorEnv("", "", "")()
+	buf := new(bytes.Buffer)
+	assert.Equal(t, false, colorWanted(ColorAuto, buf))
+	assert.Equal(t, true, colorWanted(ColorAlways, buf))
+	assert.Equal(t, false, colorWanted(ColorNever, buf))
+
+	setColorEnv("", "1", "")
+	assert.Equal(t, true, colorWanted(ColorAuto, buf))
+	assert.Equal(t, false, colorWanted(ColorNever, buf))
+	setColorEnv("", "0", "")
+	assert.Equal(t, false, colorWanted(ColorAuto, buf))
+	// NO_COLOR wins over FORCE_COLOR, but not over an explicit config
+	setColorEnv("1", "1", "")
+	assert.Equal(t, false, colorWanted(ColorAuto, buf))
+	assert.Equal(t, true, colorWanted(ColorAlways, buf))
+	// TERM=dumb turns off color even on a terminal
+	setColorEnv("", "", "dumb")
+	assert.Equal(t, false, colorWanted(ColorAuto, os.Stdout))
+
+	cfg, err := parseColorConfig("Always")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, ColorAlways, cfg)
+	_, err = parseColorConfig("sometimes")
+	assert.NotEqual(t, nil, err)
+
+	assert.Equal(t, "\x1b[31mError:\x1b[0m ", colorize("Error: ", "31"))
+	assert.Equal(t, "Error: ", stripANSI(colorize("Error: ", "31")))
+	assert.Equal(t, 7, displayWidth(colorize("Error: ", "1;31")))
+	assert.Equal(t, true, strings.HasPrefix(colorize("x", "90"), "\x1b[90m"))
+
+	// the terminal check is done once per file until the writers change
+	file, err := ioutil.TempFile("", "outcolor")
+	assert.Equal(t, nil, err)
+	defer os.Remove(file.Name())
+	assert.Equal(t, false, isTerminal(file))
+	terminals[file] = true
+	assert.Equal(t, true, isTerminal(file))
+	SetWriter(LevelAll, file, ForScreen)
+	assert.Equal(t, false, isTerminal(file))
+	// ... also when the writers are restored or set via a config
+	terminals[file] = true
+	Snapshot().Restore()
+	assert.Equal(t, false, isTerminal(file))
+	terminals[file] = true
+	assert.Equal(t, nil, ApplyConfig(&Config{}))
+	assert.Equal(t, false, isTerminal(file))
+	file.Close()
+	ResetOutPkg()
+}
+
+func TestColorConfig(t *testing.T) {
+	err := LoadConfig(strings.NewReader(`{"color": "never",
+		"levels": {"note": {"prefixColor": "1;34", "metadataColor": ""}}}`))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, ColorNever, ColorConfig())
+	prefixColor, metaColor := LevelColors(LevelNote)
+	assert.Equal(t, "1;34", prefixColor)
+	assert.Equal(t, "", metaColor)
+	stateJSON, err := json.Marshal(Snapshot())
+	assert.Equal(t, nil, err)
+	assert.Contains(t, string(stateJSON), `"colorConfig":"never"`)
+	err = LoadConfig(strings.NewReader(`{"color": "rainbow"}`))
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, ColorNever, ColorConfig())
+	ResetOutPkg()
+	assert.Equal(t, ColorAuto, ColorConfig())
+
+	outFlags, err := parseFlagSet(nil, "--color=always")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, outFlags.Apply())
+	assert.Equal(t, ColorAlways, ColorConfig())
+	ResetOutPkg()
+}
@@ -0,0 +1,363 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package out
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Config is the declarative form of the 'out' settings, typically read from
+// a tool's JSON config file via LoadConfig(), eg:
+//
+//	{
+//	  "screen":  { "threshold": "verbose", "flags": "off", "wrapWidth": 100 },
+//	  "logfile": { "threshold": "debug", "path": "~/.mytool/log.txt",
+//	               "format": "json", "maxSize": 10485760 },
+//	  "levels":  { "all": { "logfileFlags": "all", "wrap": true },
+//	               "note": { "prefix": "NOTE: " } },
+//	  "color": "auto",
+//	  "stackTraces": "logfile,nonzeroerrorexit",
+//	  "debugScope": "github.com/me/mytool/pkg"
+//	}
+//
+// The quiet and silent settings turn those screen modes on or off (see
+// SetQuiet() and SetSilent()), color is "auto", "always" or "never" (see
+// SetColorConfig()).  Anything not given is left as it is currently
+// set.
+type Config struct {
+	Screen      *TargetConfig          `json:"screen,omitempty"`
+	Logfile     *TargetConfig          `json:"logfile,omitempty"`
+	Levels      map[string]LevelConfig `json:"levels,omitempty"`
+	Quiet       *bool                  `json:"quiet,omitempty"`
+	Silent      *bool                  `json:"silent,omitempty"`
+	Color       *string                `json:"color,omitempty"`
+	StackTraces *string                `json:"stackTraces,omitempty"`
+	DebugScope  *string                `json:"debugScope,omitempty"`
+}
+
+// TargetConfig holds the settings of the screen or logfile output target,
+// the threshold is a level name (see ParseLevel()), flags are comma separated
+// flag names as in the PKG_OUT_SCREEN_FLAGS env (eg: "date,time,shortfile")
+// and apply to all levels, the format is "text", "json" or "logfmt".  The
+// logfile may be given a path (a leading "~/" is the home dir) or a temp file
+// prefix (see UseTempLogFile()) and a max size in bytes at which it rotates
+// (see RotateWriter).  The screen may be given a width to wrap at (see
+// SetWrapWidth()).
+type TargetConfig struct {
+	Threshold  string  `json:"threshold,omitempty"`
+	Flags      *string `json:"flags,omitempty"`
+	Format     string  `json:"format,omitempty"`
+	Path       string  `json:"path,omitempty"`
+	TempPrefix string  `json:"tempPrefix,omitempty"`
+	MaxSize    int64   `json:"maxSize,omitempty"`
+	WrapWidth  int     `json:"wrapWidth,omitempty"`
+}
+
+// LevelConfig holds the settings of one output level, it is keyed by the
+// level name in Config.Levels ("all" applies to every level, specific levels
+// are then applied on top of that), colors are ANSI SGR parameters for the
+// screen (see SetLevelColors()) and wrap turns soft wrapping of the screen
+// output on or off (see SetWrap())
+type LevelConfig struct {
+	Prefix        *string `json:"prefix,omitempty"`
+	ScreenFlags   *string `json:"screenFlags,omitempty"`
+	LogfileFlags  *string `json:"logfileFlags,omitempty"`
+	PrefixColor   *string `json:"prefixColor,omitempty"`
+	MetadataColor *string `json:"metadataColor,omitempty"`
+	Wrap          *bool   `json:"wrap,omitempty"`
+}
+
+// LoadConfig reads a JSON config (see Config) and applies it via ApplyConfig(),
+// unknown settings are an error (typically a typo) as are invalid values, in
+// which case none of the config is applied
+func LoadConfig(r io.Reader) error {
+	return loadConfig(r, nil)
+}
+
+// loadConfig is LoadConfig() on top of the given settings, see applyConfig()
+func loadConfig(r io.Reader, base *State) error {
+	var cfg Config
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return fmt.Errorf("invalid 'out' config: %s", err)
+	}
+	return applyConfig(&cfg, base)
+}
+
+// LoadConfigFile reads the JSON config in the named file, see LoadConfig()
+func LoadConfigFile(path string) error {
+	return loadConfigFile(path, nil)
+}
+
+// loadConfigFile is LoadConfigFile() on top of the given settings, see
+// applyConfig()
+func loadConfigFile(path string, base *State) error {
+	file, err := os.Open(expandHome(path))
+	if err != nil {
+		return fmt.Errorf("invalid 'out' config: %s", err)
+	}
+	defer file.Close()
+	return loadConfig(file, base)
+}
+
+// ApplyConfig validates the given config and, if valid, applies it as a
+// whole (output never sees part of the config applied), if invalid all of
+// the problems found are returned in one error and nothing is changed
+func ApplyConfig(cfg *Config) error {
+	return applyConfig(cfg, nil)
+}
+
+// applyConfig is ApplyConfig() on top of the given settings (left as is)
+// instead of the current ones, nil means the current settings
+func applyConfig(cfg *Config, base *State) error {
+	var errs []string
+	addErr := func(setting string, err error) {
+		errs = append(errs, setting+": "+err.Error())
+	}
+	// the lock is held throughout so settings changed by others while the
+	// config is being applied aren't lost
+	mutex.Lock()
+	defer mutex.Unlock()
+	snap := snapshotLocked()
+	if base != nil {
+		// the newline tracking isn't a setting, it stays current
+		screenNl, logfileNl := snap.screenNewline, snap.logfileNewline
+		snap = base.clone()
+		snap.screenNewline, snap.logfileNewline = screenNl, logfileNl
+	}
+
+	// per-target settings, flags given here apply to all levels
+	for _, tgt := range []struct {
+		name      string
+		cfg       *TargetConfig
+		threshold *Level
+		format    *string
+		setFlags  func(l *levelState, flags int)
+	}{
+		{"screen", cfg.Screen, &snap.screenThreshold, &snap.screenFormat, func(l *levelState, flags int) { l.screenFlags = flags }},
+		{"logfile", cfg.Logfile, &snap.logThreshold, &snap.logfileFormat, func(l *levelState, flags int) { l.logFlags = flags }},
+	} {
+		if tgt.cfg == nil {
+			continue
+		}
+		if tgt.cfg.Threshold != "" {
+			level, err := ParseLevel(tgt.cfg.Threshold)
+			if err != nil {
+				addErr(tgt.name+".threshold", err)
+			}
+			*tgt.threshold = level
+		}
+		if tgt.cfg.Flags != nil {
+			flags, err := parseFlags(*tgt.cfg.Flags)
+			if err != nil {
+				addErr(tgt.name+".flags", err)
+			}
+			for i := range snap.levels {
+				tgt.setFlags(&snap.levels[i], flags)
+			}
+		}
+		if tgt.cfg.Format != "" {
+			format, err := parseOutputFormat(tgt.cfg.Format)
+			if err != nil {
+				addErr(tgt.name+".format", err)
+			}
+			*tgt.format = format
+		}
+	}
+	if cfg.Screen != nil && (cfg.Screen.Path != "" || cfg.Screen.TempPrefix != "" || cfg.Screen.MaxSize != 0) {
+		addErr("screen", fmt.Errorf("path, tempPrefix and maxSize are only valid for the logfile"))
+	}
+	if scr := cfg.Screen; scr != nil {
+		if scr.WrapWidth < 0 {
+			addErr("screen.wrapWidth", fmt.Errorf("must not be negative (0 means the terminal width), got %d", scr.WrapWidth))
+		} else if scr.WrapWidth > 0 {
+			snap.wrapWidth = scr.WrapWidth
+		}
+	}
+	if lf := cfg.Logfile; lf != nil {
+		if lf.WrapWidth != 0 {
+			addErr("logfile.wrapWidth", fmt.Errorf("only the screen output is wrapped"))
+		}
+		if lf.Path != "" && lf.TempPrefix != "" {
+			addErr("logfile", fmt.Errorf("give a path or a tempPrefix, not both"))
+		}
+		if lf.MaxSize < 0 {
+			addErr("logfile.maxSize", fmt.Errorf("must not be negative (0 means no rotation), got %d", lf.MaxSize))
+		} else if lf.MaxSize > 0 && lf.Path == "" {
+			addErr("logfile.maxSize", fmt.Errorf("rotation needs a logfile path"))
+		}
+	}
+
+	// per-level settings, "all" first so specific levels can override it
+	names := make([]string, 0, len(cfg.Levels))
+	for name := range cfg.Levels {
+		if strings.ToLower(name) != "all" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for name, lcfg := range cfg.Levels {
+		if strings.ToLower(name) == "all" {
+			for i := range snap.levels {
+				applyLevelConfig(&snap.levels[i], "levels."+name, lcfg, addErr)
+			}
+		}
+	}
+	for _, name := range names {
+		level, err := ParseLevel(name)
+		idx := int(level - LevelTrace)
+		if err == nil && (idx < 0 || idx >= len(snap.levels)) {
+			err = fmt.Errorf("level %q has no output settings", name)
+		}
+		if err != nil {
+			addErr("levels."+name, err)
+			continue
+		}
+		applyLevelConfig(&snap.levels[idx], "levels."+name, cfg.Levels[name], addErr)
+	}
+
+	if cfg.StackTraces != nil {
+		stackCfg, err := parseStackTraceConfig(*cfg.StackTraces)
+		if err != nil {
+			addErr("stackTraces", err)
+		}
+		snap.stackTraceConfig = stackCfg
+	}
+	if cfg.DebugScope != nil {
+		snap.debugScope = *cfg.DebugScope
+	}
+	if cfg.Quiet != nil {
+		snap.quiet = *cfg.Quiet
+	}
+	if cfg.Silent != nil {
+		snap.silent = *cfg.Silent
+	}
+	if cfg.Color != nil {
+		colorCfg, err := parseColorConfig(*cfg.Color)
+		if err != nil {
+			addErr("color", err)
+		}
+		snap.colorConfig = colorCfg
+	}
+	if errs != nil {
+		return fmt.Errorf("invalid 'out' config:\n  %s", strings.Join(errs, "\n  "))
+	}
+
+	// the logfile is opened last as it's the only thing that can't be undone,
+	// the log file written to until now is closed once it has been replaced
+	// (unless applied on top of other settings, eg: a WatchConfig() reload
+	// where the starting settings may still use it, see closeUnusedLogfile())
+	var oldLogfile io.Writer
+	if lf := cfg.Logfile; lf != nil && (lf.Path != "" || lf.TempPrefix != "") {
+		if base == nil {
+			oldLogfile = logfileWriter(snap)
+		}
+		var wr io.Writer
+		var err error
+		if lf.TempPrefix != "" {
+			var file *os.File
+			if file, err = ioutil.TempFile(os.TempDir(), lf.TempPrefix); err == nil {
+				wr, snap.logFileName = file, file.Name()
+			}
+		} else {
+			path := expandHome(lf.Path)
+			if lf.MaxSize > 0 {
+				var rw *RotateWriter
+				if rw, err = openRotateWriter(path, lf.MaxSize); err == nil {
+					wr, snap.logFileName = rw, path
+				}
+			} else {
+				var file *os.File
+				if file, err = os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0666); err == nil {
+					wr, snap.logFileName = file, file.Name()
+				}
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("invalid 'out' config:\n  logfile: %s", err)
+		}
+		for i := range snap.levels {
+			snap.levels[i].logfileHndl = wr
+		}
+	}
+	snap.restoreLocked()
+	if oldLogfile != nil {
+		closeLogfile(oldLogfile)
+	}
+	return nil
+}
+
+// closeLogfile closes a log file writer opened by the 'out' package, see the
+// logfileWriter() routine
+func closeLogfile(wr io.Writer) {
+	switch wr := wr.(type) {
+	case *os.File:
+		wr.Close()
+	case *RotateWriter:
+		wr.lock.Lock()
+		wr.fp.Close()
+		wr.lock.Unlock()
+	}
+}
+
+// applyLevelConfig puts the given level config into the level state, any
+// invalid flags are reported via addErr() using the given setting name
+func applyLevelConfig(l *levelState, setting string, lcfg LevelConfig, addErr func(string, error)) {
+	if lcfg.Prefix != nil {
+		l.prefix = *lcfg.Prefix
+	}
+	if lcfg.ScreenFlags != nil {
+		flags, err := parseFlags(*lcfg.ScreenFlags)
+		if err != nil {
+			addErr(setting+".screenFlags", err)
+		}
+		l.screenFlags = flags
+	}
+	if lcfg.LogfileFlags != nil {
+		flags, err := parseFlags(*lcfg.LogfileFlags)
+		if err != nil {
+			addErr(setting+".logfileFlags", err)
+		}
+		l.logFlags = flags
+	}
+	if lcfg.PrefixColor != nil {
+		l.prefixColor = *lcfg.PrefixColor
+	}
+	if lcfg.MetadataColor != nil {
+		l.metaColor = *lcfg.MetadataColor
+	}
+	if lcfg.Wrap != nil {
+		l.wrap = *lcfg.Wrap
+	}
+}
+
+// expandHome replaces a leading "~/" in the path with the user's home dir
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
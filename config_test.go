@@ -0,0 +1,209 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package test for: out/config.go
+//   Testing in this file focuses on loading a JSON config, its validation and
+//   the structured output formats and log rotation it can turn on and the
+//   closing of the log files a config replaces
+
+package out
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dvln/testify/assert"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "outcfg")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	logPath := filepath.Join(dir, "tool.log")
+	screenBuf := new(bytes.Buffer)
+	SetWriter(LevelAll, screenBuf, ForScreen)
+
+	err = LoadConfig(strings.NewReader(`{
+		"screen":  { "threshold": "Verbose", "flags": "off" },
+		"logfile": { "threshold": "debug", "path": "` + logPath + `", "flags": "level" },
+		"levels":  { "all": { "screenFlags": "pid" },
+		             "note": { "prefix": "NOTE> ", "screenFlags": "off" } },
+		"stackTraces": "both,allissues",
+		"debugScope": "github.com/dvln/out"
+	}`))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, LevelVerbose, Threshold(ForScreen))
+	assert.Equal(t, LevelDebug, Threshold(ForLogfile))
+	assert.Equal(t, "NOTE> ", Prefix(LevelNote))
+	assert.Equal(t, "Issue: ", Prefix(LevelIssue))
+	assert.Equal(t, 0, Flags(LevelNote, ForScreen))
+	assert.Equal(t, Lpid, Flags(LevelInfo, ForScreen))
+	assert.Equal(t, Llevel, Flags(LevelNote, ForLogfile))
+	assert.Equal(t, ForBoth|StackTraceAllIssues, StackTraceConfig())
+	assert.Equal(t, "github.com/dvln/out", DebugScope())
+	assert.Equal(t, logPath, LogFileName())
+
+	Noteln("configured")
+	Debugln("not on screen")
+	ResetOutPkg()
+
+	assert.Equal(t, "NOTE> configured\n", screenBuf.String())
+	logged, err := ioutil.ReadFile(logPath)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "NOTE    NOTE> configured\nDEBUG   Debug: not on screen\n", string(logged))
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	SetThreshold(LevelNote, ForScreen)
+	err := LoadConfig(strings.NewReader(`{
+		"screen":  { "threshold": "loud", "format": "xml" },
+		"logfile": { "flags": "date,tiem", "maxSize": 100 },
+		"levels":  { "notice": { "prefix": "x" }, "note": { "prefix": "N: " } },
+		"stackTraces": "both"
+	}`))
+	assert.NotEqual(t, nil, err)
+	if err != nil {
+		msg := err.Error()
+		assert.Contains(t, msg, "invalid 'out' config:\n")
+		assert.Contains(t, msg, `screen.threshold: unknown level "loud"`)
+		assert.Contains(t, msg, `screen.format: unknown output format "xml"`)
+		assert.Contains(t, msg, `logfile.flags: unknown flag "tiem"`)
+		assert.Contains(t, msg, "logfile.maxSize: rotation needs a logfile path")
+		assert.Contains(t, msg, `levels.notice: unknown level "notice"`)
+		assert.Contains(t, msg, `stackTraces: invalid stack trace config "both"`)
+	}
+	// nothing is applied, not even the valid parts
+	assert.Equal(t, LevelNote, Threshold(ForScreen))
+	assert.Equal(t, "Note: ", Prefix(LevelNote))
+
+	err = LoadConfig(strings.NewReader(`{ "screen": { "treshold": "debug" } }`))
+	assert.NotEqual(t, nil, err)
+	if err != nil {
+		assert.Contains(t, err.Error(), `unknown field "treshold"`)
+	}
+	ResetOutPkg()
+}
+
+func TestOutputFormats(t *testing.T) {
+	screenBuf := new(bytes.Buffer)
+	logBuf := new(bytes.Buffer)
+	SetWriter(LevelAll, screenBuf, ForScreen)
+	SetWriter(LevelAll, logBuf, ForLogfile)
+	SetThreshold(LevelInfo, ForBoth)
+	assert.Equal(t, nil, SetOutputFormat(FormatJSON, ForScreen))
+	assert.Equal(t, nil, SetOutputFormat("LOGFMT", ForLogfile))
+	assert.NotEqual(t, nil, SetOutputFormat("xml", ForBoth))
+	assert.Equal(t, FormatJSON, OutputFormat(ForScreen))
+	assert.Equal(t, FormatLogfmt, OutputFormat(ForLogfile))
+
+	Noteln("a \"quoted\" note")
+	Info("no newline")
+	Issueln(NewErr("nothing specific", int(DefaultErrCode())))
+	Errorln(With(NewErr("disk full", 210), "path", "/tmp"))
+	ResetOutPkg()
+
+	screenLines := strings.Split(screenBuf.String(), "\n")
+	assert.Equal(t, 5, len(screenLines))
+	// the default error code says nothing so it's left out
+	assert.Contains(t, screenLines[2], `"level":"ISSUE","msg":"nothing specific",`)
+	assert.NotContains(t, screenLines[2], `"code"`)
+	assert.Contains(t, screenLines[0], `"level":"NOTE","msg":"a \"quoted\" note",`)
+	assert.Contains(t, screenLines[0], `"file":"config_test.go",`)
+	assert.Contains(t, screenLines[0], `"func":"github.com/dvln/out.TestOutputFormats",`)
+	assert.Contains(t, screenLines[1], `"level":"INFO","msg":"no newline",`)
+	// error fields are fields of their own, not part of the message
+	assert.Contains(t, screenLines[3], `"msg":"disk full","code":210,`)
+	assert.Contains(t, screenLines[3], `"fields":{"path":"/tmp"}`)
+	logLines := strings.Split(logBuf.String(), "\n")
+	assert.Equal(t, 5, len(logLines))
+	assert.Contains(t, logLines[3], ` msg="disk full" code=210 `)
+	assert.Contains(t, logLines[3], ` path=/tmp`)
+	assert.NotContains(t, logBuf.String()+screenBuf.String(), "Fields:")
+	assert.Contains(t, logLines[0], ` level=NOTE msg="a \"quoted\" note" file=config_test.go line=`)
+	assert.Contains(t, logLines[1], ` level=INFO msg="no newline" `)
+	assert.Equal(t, FormatText, OutputFormat(ForScreen))
+}
+
+func TestApplyConfigReplacesLogfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "outcfg")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	SetWriter(LevelAll, ioutil.Discard, ForScreen)
+
+	err = ApplyConfig(&Config{Logfile: &TargetConfig{Path: filepath.Join(dir, "first.log")}})
+	assert.Equal(t, nil, err)
+	first, ok := Writer(LevelInfo, ForLogfile).(*os.File)
+	assert.Equal(t, true, ok)
+	// the log file that is replaced is closed, not leaked
+	err = ApplyConfig(&Config{Logfile: &TargetConfig{Path: filepath.Join(dir, "second.log"), MaxSize: 100}})
+	assert.Equal(t, nil, err)
+	if ok {
+		_, err = first.Write([]byte("late\n"))
+		assert.NotEqual(t, nil, err)
+	}
+	second, ok := Writer(LevelInfo, ForLogfile).(*RotateWriter)
+	assert.Equal(t, true, ok)
+	// ... unless the new config is invalid
+	err = ApplyConfig(&Config{Logfile: &TargetConfig{Path: filepath.Join(dir, "no", "such", "dir.log")}})
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, second, Writer(LevelInfo, ForLogfile))
+	err = ApplyConfig(&Config{Logfile: &TargetConfig{Path: filepath.Join(dir, "first.log")}})
+	assert.Equal(t, nil, err)
+	if ok {
+		_, err = second.fp.Write([]byte("late\n"))
+		assert.NotEqual(t, nil, err)
+	}
+	closeLogfile(Writer(LevelInfo, ForLogfile))
+	ResetOutPkg()
+}
+
+func TestRotateWriterMaxSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "outrotate")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	logPath := filepath.Join(dir, "tool.log")
+	SetWriter(LevelAll, ioutil.Discard, ForScreen)
+
+	err = ApplyConfig(&Config{Logfile: &TargetConfig{Threshold: "info", Path: logPath, MaxSize: 20, Flags: new(string)}})
+	assert.Equal(t, nil, err)
+	rw, ok := Writer(LevelInfo, ForLogfile).(*RotateWriter)
+	assert.Equal(t, true, ok)
+	if ok {
+		assert.Equal(t, int64(20), rw.MaxSize())
+		assert.Equal(t, logPath, rw.Filename())
+	}
+	// the backups are named via the clock, rotating twice within the same
+	// second must not overwrite the first backup
+	stamp := time.Date(2016, 3, 4, 5, 6, 7, 0, time.UTC)
+	SetClock(func() time.Time { return stamp })
+	Infoln("first line")  // 11 bytes
+	Infoln("second line") // 12 bytes, rotates
+	Infoln("third line")  // 11 bytes, rotates
+	ResetOutPkg()
+
+	logged, err := ioutil.ReadFile(logPath)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "third line\n", string(logged))
+	backup := logPath + "." + stamp.Format(time.RFC3339)
+	logged, _ = ioutil.ReadFile(backup)
+	assert.Equal(t, "first line\n", string(logged))
+	logged, _ = ioutil.ReadFile(backup + ".1")
+	assert.Equal(t, "second line\n", string(logged))
+	rotated, _ := filepath.Glob(logPath + ".*")
+	assert.Equal(t, 2, len(rotated))
+}
@@ -14,9 +14,12 @@
 package out
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync/atomic"
 )
@@ -65,6 +68,46 @@ type DetailedError interface {
 	Error() string
 }
 
+// FieldedError (interface) is an optional extension of DetailedError for
+// errors that carry key/value context fields, see With().  The fields of any
+// such errors in an error chain are gathered by Fields() and included in
+// DefaultError() and structured output.  BaseError's and MultiError's
+// implement this.
+type FieldedError interface {
+	// Fields returns any key/value context attached to this error via With(),
+	// inner errors are not included (see "Fields(someErr)" for that)
+	Fields() []ErrField
+}
+
+// HintedError (interface) is an optional extension of DetailedError for errors
+// that carry user facing "what to do next" text.  If a detailed error with a
+// hint or remediation is dumped via Issue(), Error(), Fatal() and friends the
+// screen gets just the most recent error message followed by the hint and
+// remediation text (aligned under the error message), eg:
+//
+//	Error #4010: not logged in
+//	             Hint: run 'mytool login'
+//
+// While the logfile gets the full error chain and stack trace (as given by
+// DefaultError() with a stack trace) for troubleshooting.  BaseError's and
+// the errors returned by WithHint() and WithRemediation() implement this.
+type HintedError interface {
+	// Hint returns a short hint for the user as to what to do next
+	Hint() string
+
+	// Remediation returns a (longer) description of how to fix the problem
+	Remediation() string
+}
+
+// ErrField is a single bit of key/value context attached to a DetailedError
+// via With(), eg: the path of a file that couldn't be opened, the host that
+// was being contacted or the attempt # of a retry.  These travel with the
+// error as it is wrapped and passed back so root cause data isn't lost.
+type ErrField struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
 // BaseError can be used for fancier erroring (not required).  This pkg will
 // take advantage of such errors if used so that stack traces dumped are as
 // close to the originating error as possible and all error messages as errors
@@ -85,6 +128,9 @@ type BaseError struct {
 	context string
 	inner   error
 	lvlOut  *LvlOutput
+	fields  []ErrField
+	hint    string
+	remedy  string
 }
 
 // DefaultErrCode gets the current default error code if you're using
@@ -117,7 +163,10 @@ func Message(err interface{}) string {
 		detErr := DetailedError(e)
 		ret := []string{}
 		for detErr != nil {
-			ret = append(ret, detErr.Message())
+			// an empty message wrapping another error adds nothing, skip it
+			if msg := detErr.Message(); msg != "" || detErr.Inner() == nil {
+				ret = append(ret, msg)
+			}
 			i := detErr.Inner()
 			if i == nil {
 				break
@@ -177,6 +226,103 @@ func Code(err interface{}) int {
 	}
 }
 
+// Fields returns all key/value context fields attached to the error and to
+// any nested/inner errors, the basics:
+// a) fields from the "most outer" error come first followed by inner errors
+// (same ordering as the error messages themselves, see Message())
+// b) if a key is used at multiple levels the "most outer" value wins, ie: the
+// most recent context set for that key as the error was passed back
+// This is different than "detErr.Fields()" as that will get the fields for
+// that specific error only (will not recurse inner errors/etc)
+func Fields(err interface{}) []ErrField {
+	detErr, ok := err.(DetailedError)
+	if !ok {
+		return nil
+	}
+	var fields []ErrField
+	seen := make(map[string]bool)
+	for detErr != nil {
+		if fieldErr, isFielded := detErr.(FieldedError); isFielded {
+			for _, field := range fieldErr.Fields() {
+				if !seen[field.Key] {
+					seen[field.Key] = true
+					fields = append(fields, field)
+				}
+			}
+		}
+		i := detErr.Inner()
+		if i == nil {
+			break
+		}
+		detErr, ok = i.(DetailedError)
+		if !ok {
+			break
+		}
+	}
+	return fields
+}
+
+// Hint returns the first hint found in the given error or any of its nested
+// errors (most outer first), "" if no hints are available
+func Hint(err interface{}) string {
+	return hintedText(err, HintedError.Hint)
+}
+
+// Remediation returns the first remediation found in the given error or any
+// of its nested errors (most outer first), "" if none are available
+func Remediation(err interface{}) string {
+	return hintedText(err, HintedError.Remediation)
+}
+
+// hintedText walks the error and nested errors looking for any HintedError
+// with non-empty text (as returned by the given method)
+func hintedText(err interface{}, text func(HintedError) string) string {
+	detErr, ok := err.(DetailedError)
+	for ok && detErr != nil {
+		if hintErr, isHinted := detErr.(HintedError); isHinted {
+			if str := text(hintErr); str != "" {
+				return str
+			}
+		}
+		detErr, ok = detErr.Inner().(DetailedError)
+	}
+	return ""
+}
+
+// WithHint attaches a short user facing hint as to what to do next to the
+// given error, see HintedError, eg:
+//   return out.WithHint(out.WrapErr(err, "not logged in", 4010), "run 'mytool login'")
+// If the error is a BaseError the hint is set on it directly, otherwise the
+// error is wrapped (with no additional message) in a BaseError holding it.
+func WithHint(err error, hint string) DetailedError {
+	baseErr := hintableErr(err)
+	baseErr.hint = hint
+	return baseErr
+}
+
+// WithRemediation attaches user facing text describing how to fix the issue
+// to the given error, works the same as WithHint() otherwise
+func WithRemediation(err error, remediation string) DetailedError {
+	baseErr := hintableErr(err)
+	baseErr.remedy = remediation
+	return baseErr
+}
+
+// hintableErr returns the given error if it's a BaseError, otherwise it
+// is wrapped in a BaseError with no message of its own
+func hintableErr(err error) *BaseError {
+	if baseErr, ok := err.(*BaseError); ok {
+		return baseErr
+	}
+	stack, context := stackTrace(3)
+	return &BaseError{
+		stack:   stack,
+		context: context,
+		lvlOut:  ERROR,
+		inner:   err,
+	}
+}
+
 // Error returns a string with all available error information, including inner
 // errors that are wrapped by this errors and a stack trace.
 // Note: If you need more flexibility (don't want stack trace, don't want
@@ -220,6 +366,12 @@ func (e *BaseError) Inner() error {
 	return e.inner
 }
 
+// Unwrap returns the wrapped error, if there is one, so the Go errors pkg
+// errors.Is() and errors.As() can see through detailed errors
+func (e *BaseError) Unwrap() error {
+	return e.inner
+}
+
 // LvlOut returns the currently configured output level struct
 func (e *BaseError) LvlOut() *LvlOutput {
 	if e.lvlOut == nil {
@@ -239,6 +391,113 @@ func (e *BaseError) SetLvlOut(lvlOut *LvlOutput) {
 	}
 }
 
+// Hint returns the user facing hint set on this error via WithHint() (if any)
+func (e *BaseError) Hint() string {
+	return e.hint
+}
+
+// Remediation returns the user facing remediation text set on this error via
+// WithRemediation() (if any)
+func (e *BaseError) Remediation() string {
+	return e.remedy
+}
+
+// Fields returns the key/value context set on this error via With(), note that
+// this will not recurse inner/nested errors at all, see "Fields(someErr)"
+// for that functionality (vs. this being called via "detErr.Fields()")
+func (e *BaseError) Fields() []ErrField {
+	return e.fields
+}
+
+// With returns a copy of the error with the key/value context added, params
+// are given in pairs (key, value, key, value, ...) and keys should be strings
+// (if not they are turned into strings via fmt).  If an odd number of params
+// is given the last key gets a nil value.  The error itself isn't changed so
+// a shared (sentinel) error doesn't pick up the fields of every use of it.
+func (e *BaseError) With(keyvals ...interface{}) DetailedError {
+	withErr := *e
+	withErr.fields = appendFields(append([]ErrField(nil), e.fields...), keyvals...)
+	return &withErr
+}
+
+// With attaches key/value context to the given error, see FieldedError, eg:
+//   return out.With(out.WrapErr(err, "unable to read config", 2040), "path", p)
+// If the error is a BaseError a copy of it with the fields added is returned
+// (see BaseError With()), a MultiError gets the fields itself and any other
+// error is wrapped (with no additional message) in a BaseError holding them.
+// Note: NewErr(), WrapErr() and friends return a DetailedError, which has no
+// With() (adding it would break other DetailedError implementations), so
+// "out.WrapErr(err, msg).With(...)" chaining isn't available, use this.
+func With(err error, keyvals ...interface{}) DetailedError {
+	if multiErr, ok := err.(*MultiError); ok {
+		return multiErr.With(keyvals...)
+	}
+	return hintableErr(err).With(keyvals...)
+}
+
+// fieldsOf returns the fields of the error itself (not of inner errors), nil
+// if it isn't a FieldedError
+func fieldsOf(err DetailedError) []ErrField {
+	if fieldErr, ok := err.(FieldedError); ok {
+		return fieldErr.Fields()
+	}
+	return nil
+}
+
+// appendFields adds the key/value pairs given to the fields slice given and
+// returns the updated slice (any existing key is updated in place)
+func appendFields(fields []ErrField, keyvals ...interface{}) []ErrField {
+	for i := 0; i < len(keyvals); i += 2 {
+		key, ok := keyvals[i].(string)
+		if !ok {
+			key = fmt.Sprint(keyvals[i])
+		}
+		var val interface{}
+		if i+1 < len(keyvals) {
+			val = keyvals[i+1]
+		}
+		found := false
+		for idx := range fields {
+			if fields[idx].Key == key {
+				fields[idx].Value = val
+				found = true
+				break
+			}
+		}
+		if !found {
+			fields = append(fields, ErrField{Key: key, Value: val})
+		}
+	}
+	return fields
+}
+
+// fieldsString renders error fields as a single line of space separated
+// key=value pairs, values with spaces or quotes in them are quoted
+func fieldsString(fields []ErrField) string {
+	parts := []string{}
+	for _, field := range fields {
+		val := fmt.Sprintf("%v", field.Value)
+		if val == "" || strings.ContainsAny(val, " \t\n\"=") {
+			val = strconv.Quote(val)
+		}
+		parts = append(parts, field.Key+"="+val)
+	}
+	return strings.Join(parts, " ")
+}
+
+// fieldsMap turns error fields into a map, handy for structured output
+// like the FlagMetadata handed to formatters (nil if no fields)
+func fieldsMap(fields []ErrField) map[string]interface{} {
+	if len(fields) == 0 {
+		return nil
+	}
+	m := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		m[field.Key] = field.Value
+	}
+	return m
+}
+
 // NewErr returns a new BaseError initialized with the given message and
 // the current stack trace.
 func NewErr(msg string, code ...int) DetailedError {
@@ -300,6 +559,147 @@ func WrapErrf(err error, code int, format string, args ...interface{}) DetailedE
 	}
 }
 
+// jsonError is the serialized form of a detailed error chain, each inner error
+// is nested within the error that wraps it.  Any inner error that isn't a
+// DetailedError (eg: a Go stdlib error) is stored as a "plain" error with
+// just its message (and comes back as a basic Go error when unmarshalled),
+// the child errors of a MultiError are stored in the 'errors' list.
+type jsonError struct {
+	Message string       `json:"message"`
+	Code    int          `json:"code,omitempty"`
+	Level   string       `json:"level,omitempty"`
+	Stack   string       `json:"stack,omitempty"`
+	Context string       `json:"context,omitempty"`
+	Fields  []ErrField   `json:"fields,omitempty"`
+	Hint    string       `json:"hint,omitempty"`
+	Remedy  string       `json:"remediation,omitempty"`
+	Plain   bool         `json:"plain,omitempty"`
+	Inner   *jsonError   `json:"inner,omitempty"`
+	Errors  []*jsonError `json:"errors,omitempty"`
+	Policy  int          `json:"stackPolicy,omitempty"`
+}
+
+// newJSONError walks the given error chain and builds the serializable
+// form of it (works for any DetailedError, not just BaseError)
+func newJSONError(err error) *jsonError {
+	if err == nil {
+		return nil
+	}
+	detErr, ok := err.(DetailedError)
+	if !ok {
+		return &jsonError{Message: err.Error(), Plain: true}
+	}
+	jErr := &jsonError{
+		Message: detErr.Message(),
+		Stack:   detErr.Stack(),
+		Context: detErr.Context(),
+		Fields:  fieldsOf(detErr),
+		Inner:   newJSONError(detErr.Inner()),
+	}
+	if hintErr, ok := detErr.(HintedError); ok {
+		jErr.Hint = hintErr.Hint()
+		jErr.Remedy = hintErr.Remediation()
+	}
+	if multiErr, ok := detErr.(*MultiError); ok {
+		jErr.Stack = multiErr.stack
+		jErr.Policy = multiErr.StackPolicy()
+		for _, err := range multiErr.Errors() {
+			jErr.Errors = append(jErr.Errors, newJSONError(err))
+		}
+	} else if code := detErr.Code(); code != int(defaultErrCode) {
+		jErr.Code = code
+	}
+	if lvlOut := detErr.LvlOut(); lvlOut != nil {
+		jErr.Level = lvlOut.level.String()
+	}
+	return jErr
+}
+
+// toError rebuilds an error chain from the serialized form of it
+func (j *jsonError) toError() (error, error) {
+	if j.Plain {
+		return errors.New(j.Message), nil
+	}
+	var lvlOut *LvlOutput
+	if j.Level != "" {
+		level, ok := levelFromString(j.Level)
+		if !ok {
+			return nil, fmt.Errorf("invalid error level %q in JSON error", j.Level)
+		}
+		lvlOut = LevelWriter(level)
+	}
+	if j.Errors != nil {
+		m := &MultiError{stack: j.Stack, context: j.Context, fields: j.Fields, stackPolicy: j.Policy}
+		for _, jChild := range j.Errors {
+			child, err := jChild.toError()
+			if err != nil {
+				return nil, err
+			}
+			m.Append(child)
+		}
+		if lvlOut != nil {
+			m.SetLvlOut(lvlOut)
+		}
+		return m, nil
+	}
+	e := &BaseError{
+		msg:     j.Message,
+		code:    j.Code,
+		stack:   j.Stack,
+		context: j.Context,
+		fields:  j.Fields,
+		hint:    j.Hint,
+		remedy:  j.Remedy,
+		lvlOut:  ERROR,
+	}
+	if lvlOut != nil {
+		e.SetLvlOut(lvlOut)
+	}
+	if j.Inner != nil {
+		inner, err := j.Inner.toError()
+		if err != nil {
+			return nil, err
+		}
+		e.inner = inner
+	}
+	return e, nil
+}
+
+// MarshalJSON implements json.Marshaler so the full error chain (messages,
+// codes, fields, stack traces and output level) can cross process boundaries,
+// eg: a daemon returning errors to a CLI tool over a socket.  Any wrapped
+// error that is not a DetailedError is stored with just its message.
+func (e *BaseError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newJSONError(e))
+}
+
+// UnmarshalJSON implements json.Unmarshaler, rebuilding the error chain that
+// was serialized via MarshalJSON().  Inner detailed errors come back as
+// *BaseError types and any "plain" Go error as a basic Go error with the
+// same message so Code(), IsError() and MatchingErrCodes() still work, eg:
+//   var detErr out.BaseError
+//   if err := json.Unmarshal(data, &detErr); err == nil {
+//       out.Fatal(&detErr)
+//   }
+// Note: numeric field values will come back as float64 (JSON numbers)
+func (e *BaseError) UnmarshalJSON(data []byte) error {
+	var jErr jsonError
+	if err := json.Unmarshal(data, &jErr); err != nil {
+		return err
+	}
+	jErr.Plain = false // the outermost error is always a detailed error
+	err, convErr := jErr.toError()
+	if convErr != nil {
+		return convErr
+	}
+	baseErr, ok := err.(*BaseError)
+	if !ok {
+		return fmt.Errorf("JSON error is a %T, unable to unmarshal into a BaseError", err)
+	}
+	*e = *baseErr
+	return nil
+}
+
 // DefaultError is a default implementation of the Error method of the detailed
 // error interface, see "(DetailedError) Error()" in this pkg.  Unlike the
 // detailed error "Error()" method this routine has a set of parameters that
@@ -309,16 +709,31 @@ func WrapErrf(err error, code int, format string, args ...interface{}) DetailedE
 // - withStackTrace: boolean indicating if you want a stack trace w/the error,
 // note that this is the most inner stack trace (offering the most detail)
 // - shallow: boolean indicating if you want just the latest error or all errors
+// (note that any key/value fields from all errors are included either way as
+// a "Fields: key=value ..." line following the error message(s))
 // - outLvlPfx: boolean indicating if you want the standard 'out' package error
 // outLvlPfx defaults to "Error: " if no code and "Error #<code>: " if code
 // is available in the detailed error (non 0 and non-fallback).  Note that if
 // you've changed your prefix to "" or something with no ':" in it then the
 // error code will not be inserted.
 func DefaultError(e DetailedError, withStackTrace, shallow, outLvlPfx bool) string {
+	withFields := true
+	return defaultError(e, withStackTrace, shallow, outLvlPfx, withFields)
+}
+
+// defaultError is DefaultError() with the "Fields: .." line optional, eg:
+// structured output has the fields as fields of their own (see Fields())
+func defaultError(e DetailedError, withStackTrace, shallow, outLvlPfx, withFields bool) string {
+	if multiErr, ok := e.(*MultiError); ok {
+		return multiErr.defaultError(withStackTrace, shallow, outLvlPfx, withFields)
+	}
 	var errLines []string
 	var origStack string
 
 	fillErrorInfo(e, shallow, &errLines, &origStack)
+	if fields := Fields(e); withFields && len(fields) != 0 {
+		errLines = append(errLines, "Fields: "+fieldsString(fields))
+	}
 	if withStackTrace {
 		errLines = append(errLines, "")
 		errLines = append(errLines, "Stack Trace: "+origStack)
@@ -341,7 +756,9 @@ func fillErrorInfo(err error, shallow bool, errLines *[]string, origStack *strin
 
 	derr, ok := err.(DetailedError)
 	if ok {
-		if !shallow || (shallow && len(*errLines) == 0) {
+		// an empty message wrapping another error adds nothing, skip it
+		emptyWrapper := derr.Message() == "" && derr.Inner() != nil
+		if !emptyWrapper && (!shallow || (shallow && len(*errLines) == 0)) {
 			*errLines = append(*errLines, derr.Message())
 		}
 		*origStack = derr.Stack()
@@ -391,24 +808,66 @@ func RootError(ierr error) (nerr error) {
 
 // MatchingErrCodes keeps peeling away layers of errors to see if any of the
 // given error codes (each which should be set to true in the validCodes map)
-// are in use in any of the layers of errors... only try 40 deep for now.
+// are in use in any of the layers of errors (including the child errors of a
+// MultiError at any layer)... only try 500 deep for now.
 func MatchingErrCodes(err error, validCodes map[int]bool) bool {
-	errCodeFound := false
-	for i := 0; i < 500; i++ {
-		nextErr := unwrapError(err)
-		if nextErr == nil {
-			break
+	for i := 0; i < 500 && err != nil; i++ {
+		// the child errors of a MultiError (or the like) are checked too
+		if multiErr, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, child := range multiErr.Unwrap() {
+				if MatchingErrCodes(child, validCodes) {
+					return true
+				}
+			}
+			return false
 		}
 		if detErr, ok := err.(DetailedError); ok {
-			currCode := detErr.Code()
-			if validCodes[currCode] {
-				errCodeFound = true
-				break
+			if validCodes[detErr.Code()] {
+				return true
+			}
+		}
+		err = nextError(err)
+	}
+	return false
+}
+
+// matchingRootError keeps peeling away layers of errors to see if the root
+// error is the given error constant, the child errors of a MultiError at any
+// layer are checked too (each has its own root error)... only try 500 deep
+func matchingRootError(err, errConst error) bool {
+	for i := 0; i < 500 && err != nil; i++ {
+		if err == errConst {
+			return true
+		}
+		if multiErr, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, child := range multiErr.Unwrap() {
+				if matchingRootError(child, errConst) {
+					return true
+				}
 			}
+			return false
+		}
+		next := nextError(err)
+		if next == nil {
+			// Must rely on string equivalence, otherwise a value is not
+			// equal to its pointer value.
+			return err.Error() == errConst.Error()
 		}
-		err = nextErr
+		err = next
+	}
+	return false
+}
+
+// nextError returns the error wrapped by the given error (see unwrapError()),
+// including errors wrapped via the Go "Unwrap() error" convention
+func nextError(err error) error {
+	if next := unwrapError(err); next != nil {
+		return next
+	}
+	if wrapper, ok := err.(interface{ Unwrap() error }); ok {
+		return wrapper.Unwrap()
 	}
-	return errCodeFound
+	return nil
 }
 
 // IsError performs a deep check, unwrapping errors as much as possible and
@@ -445,16 +904,7 @@ func IsError(err, errConst error, codes ...int) bool {
 	if errConst == nil {
 		return false
 	}
-	// Must rely on string equivalence, otherwise a value is not equal
-	// to its pointer value.
-	rootErrStr := ""
-	rootErr := RootError(err)
-	if rootErr != nil {
-		rootErrStr = rootErr.Error()
-	}
-	errConstStr := ""
-	if errConst != nil {
-		errConstStr = errConst.Error()
-	}
-	return rootErrStr == errConstStr
+	// The root error of any of the child errors of a MultiError (or the like)
+	// in the chain may match
+	return matchingRootError(err, errConst)
 }
@@ -19,7 +19,9 @@ package out
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"reflect"
 	"strings"
 	"syscall"
@@ -285,3 +287,124 @@ func TestRootError(t *testing.T) {
 		t.Fatalf("expected ECONNREFUSED on valid nested error: %T %v", err, err)
 	}
 }
+
+func TestErrorFields(t *testing.T) {
+	sentinel := NewErr("open failed", 2040)
+	inner := With(sentinel, "path", "/tmp/my file", "attempt", 1)
+	middle := With(WrapErr(inner, "unable to load config"), "attempt", 3, "host")
+	outer := With(WrapErr(middle, "sync failed", 3010), "repo", "foo")
+
+	innerCnt := len(inner.(FieldedError).Fields())
+	middleCnt := len(middle.(FieldedError).Fields())
+	outerCnt := len(outer.(FieldedError).Fields())
+	if innerCnt != 2 || middleCnt != 2 || outerCnt != 1 {
+		t.Errorf("unexpected per-error field counts: %d, %d, %d", innerCnt, middleCnt, outerCnt)
+	}
+	// the error given to With() isn't changed, a copy gets the fields
+	assert.Equal(t, 0, len(Fields(sentinel)))
+	assert.Equal(t, Message(sentinel), Message(inner))
+	// plain Go errors are wrapped to hold the fields
+	assert.Equal(t, []ErrField{{"host", "db1"}}, Fields(With(io.EOF, "host", "db1")))
+
+	// outermost errors come first and the most recent value for a key wins
+	fields := Fields(outer)
+	expected := []ErrField{{"repo", "foo"}, {"attempt", 3}, {"host", nil}, {"path", "/tmp/my file"}}
+	assert.Equal(t, fields, expected)
+	if Fields(fmt.Errorf("plain error")) != nil {
+		t.Error("plain Go errors should have no fields")
+	}
+
+	errStr := DefaultError(outer, false, false, true)
+	assert.Contains(t, errStr, "Error #3010: sync failed\n")
+	assert.Contains(t, errStr, "Error #3010: Fields: repo=foo attempt=3 host=<nil> path=\"/tmp/my file\"")
+
+	screenBuf := new(bytes.Buffer)
+	SetWriter(LevelAll, screenBuf, ForScreen)
+	SetFlags(LevelAll, 0, ForScreen)
+	Issueln(outer)
+
+	// Now reset the most common things for the 'out' pkg so the next test
+	// func will operate sanely as if we're coming in fresh
+	ResetOutPkg()
+
+	assert.Contains(t, screenBuf.String(), "Issue #3010: Fields: repo=foo attempt=3")
+}
+
+func TestErrorJSON(t *testing.T) {
+	middle := With(WrapErr(io.EOF, "short read on socket", 400), "host", "db1")
+	outer := WrapErr(middle, "unable to load data", 200)
+	outer.SetLvlOut(FATAL)
+
+	data, err := json.Marshal(outer)
+	if err != nil {
+		t.Fatalf("failed to marshal detailed error: %v", err)
+	}
+	var detErr BaseError
+	if err = json.Unmarshal(data, &detErr); err != nil {
+		t.Fatalf("failed to unmarshal detailed error: %v\n%s", err, data)
+	}
+
+	// insure it's still a fully functional detailed error on the other side
+	var newErr DetailedError = &detErr
+	assert.Equal(t, Message(newErr), Message(outer))
+	assert.Equal(t, newErr.Stack(), outer.Stack())
+	assert.Equal(t, newErr.Code(), 200)
+	assert.Equal(t, Code(newErr), 200)
+	assert.Equal(t, newErr.LvlOut(), FATAL)
+	assert.Equal(t, Fields(newErr), []ErrField{{"host", "db1"}})
+	if !IsError(newErr, io.EOF) {
+		t.Errorf("root error no longer matches io.EOF after unmarshal:\n%s", data)
+	}
+	if !IsError(newErr, nil, 400) || IsError(newErr, nil, 300) {
+		t.Errorf("error codes failed to match as expected after unmarshal:\n%s", data)
+	}
+	if !MatchingErrCodes(newErr, map[int]bool{200: true}) {
+		t.Errorf("error code 200 not found after unmarshal:\n%s", data)
+	}
+
+	if err = json.Unmarshal([]byte(`{"message":"x","level":"BOGUS"}`), &detErr); err == nil {
+		t.Error("expected an error unmarshalling an invalid error level")
+	}
+}
+
+func TestErrorHints(t *testing.T) {
+	tokenErr := NewErr("token expired for user joe", 401)
+	loginErr := WithHint(WrapErr(tokenErr, "not logged in", 4010), "run 'mytool login'")
+	assert.Equal(t, Hint(loginErr), "run 'mytool login'")
+	assert.Equal(t, Remediation(loginErr), "")
+
+	// non-BaseError errors are wrapped, the wrapper adds no message of its own
+	plainErr := WithRemediation(fmt.Errorf("disk full"), "free up space in /tmp")
+	assert.Equal(t, Message(plainErr), "disk full")
+	assert.Equal(t, Remediation(WrapErr(plainErr, "write failed")), "free up space in /tmp")
+
+	screenBuf := new(bytes.Buffer)
+	logBuf := new(bytes.Buffer)
+	SetWriter(LevelAll, screenBuf, ForScreen)
+	SetWriter(LevelAll, logBuf, ForLogfile)
+	SetThreshold(LevelInfo, ForLogfile)
+	SetFlags(LevelAll, 0, ForBoth)
+
+	Errorln(loginErr)
+
+	// Now reset the most common things for the 'out' pkg so the next test
+	// func will operate sanely as if we're coming in fresh
+	ResetOutPkg()
+	SetFlags(LevelAll, LlogfileFlags, ForLogfile)
+
+	assert.Equal(t, screenBuf.String(), "Error #4010: not logged in\n             Hint: run 'mytool login'\n")
+	assert.Contains(t, logBuf.String(), "Error #4010: not logged in\n")
+	assert.Contains(t, logBuf.String(), "Error #4010: token expired for user joe\n")
+	assert.Contains(t, logBuf.String(), "Error #4010: Stack Trace: ")
+	assert.NotContains(t, logBuf.String(), "Hint:")
+
+	// the error is rendered where it was given, not wherever its text is
+	screenBuf.Reset()
+	SetWriter(LevelAll, screenBuf, ForScreen)
+	SetFlags(LevelAll, 0, ForScreen)
+	Errorf("%s: %v", loginErr.Error(), loginErr)
+	ResetOutPkg()
+	assert.Equal(t, screenBuf.String(), "Error #4010: not logged in\n"+
+		"Error #4010: token expired for user joe: not logged in\n"+
+		"             Hint: run 'mytool login'")
+}
@@ -0,0 +1,232 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package out
+
+import (
+	"flag"
+	"strconv"
+	"strings"
+)
+
+// Set satisfies the flag.Value interface so a Level can be used as a command
+// line option, eg: fs.Var(&level, "level", "output level"), the level names
+// are case insensitive (see ParseLevel())
+func (l *Level) Set(s string) error {
+	level, err := ParseLevel(s)
+	if err != nil {
+		return err
+	}
+	*l = level
+	return nil
+}
+
+// FlagsValue is a set of output flags (Ldate, Ltime, ..) that can be used as
+// a command line option, the flag names are comma separated and are those of
+// the PKG_OUT_SCREEN_FLAGS env setting (eg: "date,time,shortfile"), eg:
+//
+//	var logFlags out.FlagsValue = out.LlogfileFlags
+//	fs.Var(&logFlags, "log-flags", "log file metadata flags")
+//	...
+//	out.SetFlags(out.LevelAll, int(logFlags), out.ForLogfile)
+type FlagsValue int
+
+// String returns the comma separated flag names
+func (f *FlagsValue) String() string {
+	if f == nil {
+		return ""
+	}
+	return strings.Join(flagNames(int(*f)), ",")
+}
+
+// Set satisfies the flag.Value interface, unknown flag names are an error
+func (f *FlagsValue) Set(s string) error {
+	flags, err := parseFlags(s)
+	if err != nil {
+		return err
+	}
+	*f = FlagsValue(flags)
+	return nil
+}
+
+// countValue is a boolean command line option that counts how often it is
+// given (eg: "-v -v"), it can also be given a count (eg: "-v=3")
+type countValue int
+
+func (c *countValue) String() string {
+	if c == nil {
+		return "0"
+	}
+	return strconv.Itoa(int(*c))
+}
+
+func (c *countValue) Set(s string) error {
+	if on, err := strconv.ParseBool(s); err == nil {
+		if on {
+			*c++
+		} else {
+			*c = 0
+		}
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return strconv.ErrSyntax
+	}
+	*c = countValue(n)
+	return nil
+}
+
+func (c *countValue) IsBoolFlag() bool { return true }
+
+// FlagOptions adjusts the options added by RegisterFlags()
+type FlagOptions struct {
+	// Prefix is put in front of the long option names, eg: "out-" gives
+	// "--out-log-file", the short options are not prefixed
+	Prefix string
+	// NoShort leaves out the short options (-v, -D and -q) for tools that
+	// use those letters for something else
+	NoShort bool
+}
+
+// CLIFlags holds the values of the command line options added by
+// RegisterFlags(), once the options are parsed Apply() configures the 'out'
+// package with them
+type CLIFlags struct {
+	Verbose     int    // -v/--verbose count
+	Debug       bool   // -D/--debug
+	Quiet       bool   // -q/--quiet
+	LogFile     string // --log-file
+	LogLevel    Level  // --log-level
+	LogFormat   string // --log-format
+	StackTraces string // --stack-traces
+	DebugScope  string // --debug-scope
+	Color       string // --color
+	fs          *flag.FlagSet
+	prefix      string
+}
+
+// RegisterFlags adds the common output related command line options to the
+// given flag set (flag.CommandLine if nil), eg:
+//
+//	outFlags := out.RegisterFlags(nil, nil)
+//	flag.Parse()
+//	if err := outFlags.Apply(); err != nil {
+//		out.Fatalln(err)
+//	}
+//
+// The options added are:
+//
+//	-v, --verbose       verbose output, give twice for debug and three
+//	                    times for trace output (eg: "-v -v")
+//	-D, --debug         debug output, trace output if combined with -v
+//	-q, --quiet         quiet mode, only show issues, errors and fatal
+//	                    errors (and must-show output, see SetQuiet())
+//	--log-file path     log to the given file
+//	--log-level level   the log file threshold (default: info)
+//	--log-format fmt    the log file format: text, json or logfmt
+//	--stack-traces cfg  the stack trace config, eg: "both,allissues"
+//	--debug-scope scope limit debug and trace output to the given pkgs/funcs
+//	--color when        color screen output: auto, always or never
+//
+// See FlagOptions to prefix the long names or leave out the short ones.
+// Note that the Go flag package doesn't combine short options, ie: use
+// "-D -v" and not "-Dv".
+func RegisterFlags(fs *flag.FlagSet, opts *FlagOptions) *CLIFlags {
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+	if opts == nil {
+		opts = &FlagOptions{}
+	}
+	f := &CLIFlags{LogLevel: LevelInfo, fs: fs, prefix: opts.Prefix}
+	pfx := opts.Prefix
+	verboseUsage := "verbose output, give twice for debug and three times for trace output"
+	debugUsage := "debug output, trace output if combined with -v"
+	quietUsage := "quiet mode, only show issues, errors and fatal errors"
+	fs.Var((*countValue)(&f.Verbose), pfx+"verbose", verboseUsage)
+	fs.BoolVar(&f.Debug, pfx+"debug", false, debugUsage)
+	fs.BoolVar(&f.Quiet, pfx+"quiet", false, quietUsage)
+	if !opts.NoShort {
+		fs.Var((*countValue)(&f.Verbose), "v", verboseUsage)
+		fs.BoolVar(&f.Debug, "D", false, debugUsage)
+		fs.BoolVar(&f.Quiet, "q", false, quietUsage)
+	}
+	fs.StringVar(&f.LogFile, pfx+"log-file", "", "log to the given file")
+	fs.Var(&f.LogLevel, pfx+"log-level", "the log file `level` (trace, debug, verbose, info, note, issue, error, fatal)")
+	fs.Func(pfx+"log-format", "the log file `format`: text, json or logfmt (default text)", func(s string) error {
+		format, err := parseOutputFormat(s)
+		f.LogFormat = format
+		return err
+	})
+	fs.Func(pfx+"stack-traces", "the stack trace `config`, eg: \"both,allissues\" or \"off\"", func(s string) error {
+		_, err := parseStackTraceConfig(s)
+		f.StackTraces = s
+		return err
+	})
+	fs.StringVar(&f.DebugScope, pfx+"debug-scope", "", "limit debug and trace output to the given (comma separated) pkgs/funcs")
+	fs.Func(pfx+"color", "`when` to color screen output: auto, always or never (default auto)", func(s string) error {
+		_, err := parseColorConfig(s)
+		f.Color = s
+		return err
+	})
+	return f
+}
+
+// ScreenThreshold returns the screen threshold selected by the verbosity
+// options, Info if none of them were given (-q turns on quiet mode instead,
+// see SetQuiet())
+func (f *CLIFlags) ScreenThreshold() Level {
+	switch {
+	case f.Debug && f.Verbose > 0, f.Verbose >= 3:
+		return LevelTrace
+	case f.Debug, f.Verbose == 2:
+		return LevelDebug
+	case f.Verbose == 1:
+		return LevelVerbose
+	}
+	return LevelInfo
+}
+
+// Apply configures the 'out' package from the parsed options in one go
+// (see ApplyConfig()), only the options given on the command line are
+// applied so the other settings are left alone
+func (f *CLIFlags) Apply() error {
+	given := make(map[string]bool)
+	f.fs.Visit(func(fl *flag.Flag) {
+		given[strings.TrimPrefix(fl.Name, f.prefix)] = true
+	})
+	cfg := &Config{}
+	if given["verbose"] || given["v"] || given["debug"] || given["D"] {
+		cfg.Screen = &TargetConfig{Threshold: f.ScreenThreshold().String()}
+	}
+	if given["quiet"] || given["q"] {
+		cfg.Quiet = &f.Quiet
+	}
+	if given["log-file"] || given["log-level"] || given["log-format"] {
+		cfg.Logfile = &TargetConfig{Path: f.LogFile, Format: f.LogFormat}
+		if given["log-file"] || given["log-level"] {
+			cfg.Logfile.Threshold = f.LogLevel.String()
+		}
+	}
+	if given["stack-traces"] {
+		cfg.StackTraces = &f.StackTraces
+	}
+	if given["debug-scope"] {
+		cfg.DebugScope = &f.DebugScope
+	}
+	if given["color"] {
+		cfg.Color = &f.Color
+	}
+	return ApplyConfig(cfg)
+}
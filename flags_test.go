@@ -0,0 +1,111 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package test for: out/flags.go
+//   Testing in this file focuses on the command line options for the output
+//   settings and the flag.Value types for levels and output flags
+
+package out
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dvln/testify/assert"
+)
+
+// parseFlagSet registers the 'out' options in a new flag set and parses args
+func parseFlagSet(opts *FlagOptions, args ...string) (*CLIFlags, error) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	outFlags := RegisterFlags(fs, opts)
+	return outFlags, fs.Parse(args)
+}
+
+func TestFlagValues(t *testing.T) {
+	var level Level
+	assert.Equal(t, nil, level.Set("Debug"))
+	assert.Equal(t, LevelDebug, level)
+	assert.NotEqual(t, nil, level.Set("loud"))
+	assert.Equal(t, LevelDebug, level)
+
+	var flags FlagsValue
+	assert.Equal(t, nil, flags.Set("time, shortfile"))
+	assert.Equal(t, FlagsValue(Ltime|Lshortfile), flags)
+	assert.Equal(t, "time,shortfile", flags.String())
+	assert.NotEqual(t, nil, flags.Set("tiem"))
+}
+
+func TestRegisterFlags(t *testing.T) {
+	for _, test := range []struct {
+		args  []string
+		level Level
+	}{
+		{nil, LevelInfo},
+		{[]string{"-v"}, LevelVerbose},
+		{[]string{"-v", "-v"}, LevelDebug},
+		{[]string{"--verbose=3"}, LevelTrace},
+		{[]string{"-D"}, LevelDebug},
+		{[]string{"-D", "-v"}, LevelTrace},
+		{[]string{"-q"}, LevelInfo},
+	} {
+		outFlags, err := parseFlagSet(nil, test.args...)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, test.level, outFlags.ScreenThreshold(), "args: %v", test.args)
+	}
+
+	dir, err := ioutil.TempDir("", "outflags")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	logPath := filepath.Join(dir, "tool.log")
+	outFlags, err := parseFlagSet(nil, "-D", "--log-file", logPath, "--log-format=json",
+		"--stack-traces=both,allissues", "--debug-scope", "github.com/dvln/out")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, outFlags.Apply())
+	assert.Equal(t, LevelDebug, Threshold(ForScreen))
+	assert.Equal(t, LevelInfo, Threshold(ForLogfile))
+	assert.Equal(t, logPath, LogFileName())
+	assert.Equal(t, FormatJSON, OutputFormat(ForLogfile))
+	assert.Equal(t, ForBoth|StackTraceAllIssues, StackTraceConfig())
+	assert.Equal(t, "github.com/dvln/out", DebugScope())
+	ResetOutPkg()
+
+	outFlags, err = parseFlagSet(nil, "-q")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, outFlags.Apply())
+	assert.Equal(t, true, Quiet())
+	assert.Equal(t, LevelInfo, Threshold(ForScreen))
+	ResetOutPkg()
+
+	// only the options given are applied
+	SetThreshold(LevelNote, ForScreen)
+	outFlags, err = parseFlagSet(&FlagOptions{Prefix: "out-", NoShort: true}, "--out-log-level", "debug")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, outFlags.Apply())
+	assert.Equal(t, LevelNote, Threshold(ForScreen))
+	assert.Equal(t, LevelDebug, Threshold(ForLogfile))
+	ResetOutPkg()
+
+	// bad values are caught when parsing
+	_, err = parseFlagSet(nil, "--log-level", "loud")
+	assert.NotEqual(t, nil, err)
+	_, err = parseFlagSet(nil, "--log-format", "xml")
+	assert.NotEqual(t, nil, err)
+	_, err = parseFlagSet(nil, "--stack-traces", "sometimes")
+	assert.NotEqual(t, nil, err)
+	_, err = parseFlagSet(&FlagOptions{NoShort: true}, "-v")
+	assert.NotEqual(t, nil, err)
+}
@@ -0,0 +1,168 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package out
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// These are the output formats available for the screen and logfile output
+// targets, see SetOutputFormat().  The text format is the normal prefixed
+// output, the JSON and logfmt formats write one structured record per line
+// with the message and its metadata (time, level, file/line, etc).
+const (
+	FormatText   = "text"
+	FormatJSON   = "json"
+	FormatLogfmt = "logfmt"
+)
+
+// OutputFormat returns the output format of the given target, you must give
+// one or the other (out.ForScreen or out.ForLogfile) only
+func OutputFormat(outputTgt int) string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	format := logfileFormat
+	if outputTgt&ForScreen != 0 {
+		format = screenFormat
+	}
+	if format == "" {
+		format = FormatText
+	}
+	return format
+}
+
+// SetOutputFormat sets the output format (FormatText, FormatJSON or
+// FormatLogfmt) for the screen and/or logfile output targets (ForScreen,
+// ForLogfile or ForBoth), an error is returned for an unknown format.  Note
+// that any formatter set up for a level takes precedence over the structured
+// formats for the targets it formats (see SetFormatter()).
+func SetOutputFormat(format string, outputTgt int) error {
+	format, err := parseOutputFormat(format)
+	if err != nil {
+		return err
+	}
+	mutex.Lock()
+	{
+		if outputTgt&ForScreen != 0 {
+			screenFormat = format
+		}
+		if outputTgt&ForLogfile != 0 {
+			logfileFormat = format
+		}
+	}
+	mutex.Unlock()
+	return nil
+}
+
+// parseOutputFormat checks the given output format name, "" maps to text
+func parseOutputFormat(format string) (string, error) {
+	switch strings.ToLower(format) {
+	case "", FormatText:
+		return FormatText, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	case FormatLogfmt:
+		return FormatLogfmt, nil
+	}
+	return "", fmt.Errorf("unknown output format %q (valid: %s, %s, %s)", format, FormatText, FormatJSON, FormatLogfmt)
+}
+
+// structuredRecord is the JSON form of a message in the JSON output format
+type structuredRecord struct {
+	Time        string                 `json:"time"`
+	Level       string                 `json:"level"`
+	Msg         string                 `json:"msg"`
+	Code        int                    `json:"code,omitempty"`
+	File        string                 `json:"file,omitempty"`
+	Line        int                    `json:"line,omitempty"`
+	Func        string                 `json:"func,omitempty"`
+	PID         int                    `json:"pid"`
+	Fields      map[string]interface{} `json:"fields,omitempty"`
+	Hint        string                 `json:"hint,omitempty"`
+	Remediation string                 `json:"remediation,omitempty"`
+	Stack       string                 `json:"stack,omitempty"`
+}
+
+// structuredOutput encodes a message and its metadata in the given structured
+// format (FormatJSON or FormatLogfmt) as a single newline terminated line, the
+// code is only included if non-zero and not the default error code (see
+// SetDefaultErrCode()) and the stack only if non-empty, any detailed error
+// fields are included as given (see Fields())
+func structuredOutput(format string, msg string, code int, stack string, fields []ErrField, mdata *FlagMetadata) string {
+	if code == int(DefaultErrCode()) {
+		code = 0
+	}
+	rec := structuredRecord{
+		Level:       mdata.Level,
+		Msg:         strings.TrimSuffix(msg, "\n"),
+		Code:        code,
+		File:        mdata.File,
+		Line:        mdata.LineNo,
+		Func:        mdata.Func,
+		PID:         mdata.PID,
+		Fields:      fieldsMap(fields),
+		Hint:        mdata.Hint,
+		Remediation: mdata.Remediation,
+		Stack:       strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(stack), "Stack Trace:")),
+	}
+	if mdata.Time != nil {
+		rec.Time = mdata.Time.Format(time.RFC3339Nano)
+	}
+	if format == FormatJSON {
+		line, err := json.Marshal(rec)
+		if err != nil {
+			// fields that can't be encoded (eg: a func), fall back to text
+			rec.Fields = map[string]interface{}{"error": fieldsString(fields)}
+			line, _ = json.Marshal(rec)
+		}
+		return string(line) + "\n"
+	}
+	kvs := []ErrField{{"time", rec.Time}, {"level", rec.Level}, {"msg", rec.Msg}}
+	if rec.Code != 0 {
+		kvs = append(kvs, ErrField{"code", rec.Code})
+	}
+	if rec.File != "" {
+		kvs = append(kvs, ErrField{"file", rec.File}, ErrField{"line", rec.Line}, ErrField{"func", rec.Func})
+	}
+	kvs = append(kvs, ErrField{"pid", rec.PID})
+	kvs = append(kvs, fields...)
+	if rec.Hint != "" {
+		kvs = append(kvs, ErrField{"hint", rec.Hint})
+	}
+	if rec.Remediation != "" {
+		kvs = append(kvs, ErrField{"remediation", rec.Remediation})
+	}
+	if rec.Stack != "" {
+		kvs = append(kvs, ErrField{"stack", rec.Stack})
+	}
+	return fieldsString(kvs) + "\n"
+}
+
+// structuredRecords formats the message as a structured record, see
+// structuredOutput(), unless records are given (eg: the rows of a table) in
+// which case there is one record per entry with the entry's fields added
+func structuredRecords(format string, msg string, code int, stack string, fields []ErrField, mdata *FlagMetadata, records [][]ErrField) string {
+	if records == nil {
+		return structuredOutput(format, msg, code, stack, fields, mdata)
+	}
+	var out strings.Builder
+	for _, record := range records {
+		recFields := append(append([]ErrField{}, fields...), record...)
+		out.WriteString(structuredOutput(format, tableRowMsg, code, "", recFields, mdata))
+	}
+	return out.String()
+}
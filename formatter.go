@@ -81,6 +81,24 @@ func SetFormatter(level Level, formatter Formatter) {
 	}
 }
 
+// LevelFormatter returns the formatter set for the given level (nil if none),
+// see SetFormatter()
+func LevelFormatter(level Level) Formatter {
+	level = levelCheck(level)
+	var formatter Formatter
+	for _, o := range outputters {
+		o.mu.RLock()
+		lvlFormatter := o.formatter
+		outLvl := o.level
+		o.mu.RUnlock()
+		if outLvl == level {
+			formatter = lvlFormatter
+			break
+		}
+	}
+	return formatter
+}
+
 // ClearFormatter clears the formatters on a given level or all levels
 // if the LevelAll level is used.
 func ClearFormatter(level Level) {
@@ -33,6 +33,7 @@ type killScreenOut struct{}
 type replaceMsg struct{}
 type detectDying struct{}
 type logOnlyFormatMsg struct{}
+type fieldsFormatMsg struct{}
 
 // FormatMessage in this context is to test the formatting "feature" of
 // the 'out' package.  In this case we're suppressing all screen output
@@ -76,6 +77,16 @@ func (f detectDying) FormatMessage(msg string, outLevel Level, code int, dying b
 	return msg, applyMask, suppressOutputMask, suppressNativePrefixing
 }
 
+// FormatMessage in this context is to test that any detailed error key/value
+// fields are made available to the formatter via the metadata
+func (f fieldsFormatMsg) FormatMessage(msg string, outLevel Level, code int, dying bool, mdata FlagMetadata) (string, int, int, bool) {
+	msg = fmt.Sprintf("fields: %v\n", mdata.Fields)
+	applyMask := ForLogfile
+	suppressOutputMask := 0
+	suppressNativePrefixing := true
+	return msg, applyMask, suppressOutputMask, suppressNativePrefixing
+}
+
 func TestFormatter(t *testing.T) {
 	// Aside: if you want to see nested error messages one could create errors
 	// something like this for each level (ie: extend DetailedError with your
@@ -194,3 +205,26 @@ func TestFormatter(t *testing.T) {
 	// func will operate sanely as if we're coming in fresh
 	ResetOutPkg()
 }
+
+func TestFormatterFields(t *testing.T) {
+	detErr := With(WrapErr(With(NewErr("low level"), "path", "/a/b"), "high level"), "attempt", 2)
+
+	screenBuf := new(bytes.Buffer)
+	logfileBuf := new(bytes.Buffer)
+	SetWriter(LevelAll, screenBuf, ForScreen)
+	SetWriter(LevelAll, logfileBuf, ForLogfile)
+	SetThreshold(LevelTrace, ForLogfile)
+	var fieldsFormatter fieldsFormatMsg
+	SetFormatter(LevelAll, fieldsFormatter)
+
+	Error(detErr)
+	Note("no error here\n")
+
+	// Reset the most common things for the 'out' pkg so the next test
+	// func will operate sanely as if we're coming in fresh
+	ResetOutPkg()
+
+	assert.Contains(t, screenBuf.String(), "Error: Fields: attempt=2 path=/a/b")
+	assert.Contains(t, logfileBuf.String(), "fields: map[attempt:2 path:/a/b]\n")
+	assert.Contains(t, logfileBuf.String(), "fields: map[]\n")
+}
@@ -0,0 +1,273 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package out
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// groupMu protects the open group of each goroutine
+	groupMu sync.Mutex
+
+	// groups holds the open group of each goroutine by id
+	groups = make(map[uint64]*GroupScope)
+
+	// groupGoroutines is the number of goroutines with an open group, so
+	// output can skip looking for groups (atomic)
+	groupGoroutines int32
+
+	// groupBufferSize is how much screen output a group buffers before it's
+	// flushed, see SetGroupBufferSize() (protected by the 'out' mutex)
+	groupBufferSize = 64 * 1024
+)
+
+// GroupScope is an open group of output, see Group(), its buffered screen
+// output and newline tracking are protected by the 'out' mutex
+type GroupScope struct {
+	name    string
+	tagged  bool   // put "[name] " in front of the screen output
+	gid     uint64 // the goroutine that opened the group
+	chunks  []groupChunk
+	size    int
+	newline bool // the group's screen output is on a new line
+	ended   bool
+}
+
+// groupChunk is buffered screen output for one screen writer
+type groupChunk struct {
+	w io.Writer
+	b []byte
+}
+
+// Group starts a group of output for a task run by the calling goroutine
+// (eg: a job run in parallel with others), the screen output of the
+// goroutine is buffered and written in one go when the group ends so the
+// output of the tasks doesn't interleave, eg:
+//
+//	go func() {
+//		g := out.Group("job-3").Tagged()
+//		defer g.End()
+//		out.Println("Building")  // "[job-3] Building", shown at g.End()
+//		...
+//	}()
+//
+// The buffered output is also written once it passes the buffer size (see
+// SetGroupBufferSize()) at the end of a line, before a prompt (see Prompt())
+// and before the 'out' package exits the program.  Each group tracks its
+// own newlines so the prefixes of its lines aren't thrown off by the output
+// of other goroutines.  Log file output isn't buffered, it has the group
+// name in front of it instead (eg: "[job-3] Building") and structured
+// formats get a "group" field.  Starting a group ends any group the
+// goroutine started before, use Join() to add other goroutines to the group.
+func Group(name string) *GroupScope {
+	g := &GroupScope{name: name, gid: goroutineID(), newline: true}
+	groupMu.Lock()
+	prev := groups[g.gid]
+	groupMu.Unlock()
+	if prev != nil && prev.gid == g.gid {
+		prev.End()
+	}
+	groupMu.Lock()
+	setGroup(g.gid, g)
+	groupMu.Unlock()
+	return g
+}
+
+// Tagged puts "[name] " in front of each line of the group's screen output,
+// it returns the GroupScope for chaining
+func (g *GroupScope) Tagged() *GroupScope {
+	mutex.Lock()
+	{
+		g.tagged = true
+	}
+	mutex.Unlock()
+	return g
+}
+
+// Name returns the name of the group
+func (g *GroupScope) Name() string {
+	return g.name
+}
+
+// Flush writes the buffered screen output of the group, output is best
+// flushed at the end of a line (as End() and the buffer size limit do)
+func (g *GroupScope) Flush() {
+	mutex.Lock()
+	{
+		g.flushLocked()
+	}
+	mutex.Unlock()
+}
+
+// End writes the buffered screen output of the group (ending it with a
+// newline if needed) and ends the group, any later output goes straight to
+// the screen, ending a group again does nothing
+func (g *GroupScope) End() {
+	mutex.Lock()
+	{
+		if !g.newline && g.size != 0 {
+			g.bufferLocked(g.chunks[len(g.chunks)-1].w, []byte("\n"))
+			g.newline = true
+		}
+		g.flushLocked()
+		g.ended = true
+	}
+	mutex.Unlock()
+	groupMu.Lock()
+	if groups[g.gid] == g {
+		setGroup(g.gid, nil)
+	}
+	groupMu.Unlock()
+}
+
+// Join puts the output of the calling goroutine in the group too (eg: for a
+// helper started by the task), call the returned func when done:
+//
+//	go func() {
+//		defer g.Join()()
+//		...
+//	}()
+func (g *GroupScope) Join() func() {
+	gid := goroutineID()
+	groupMu.Lock()
+	prev := groups[gid]
+	setGroup(gid, g)
+	groupMu.Unlock()
+	return func() {
+		groupMu.Lock()
+		if groups[gid] == g {
+			setGroup(gid, prev)
+		}
+		groupMu.Unlock()
+	}
+}
+
+// GroupBufferSize returns how much screen output a group buffers before it's
+// written, see SetGroupBufferSize()
+func GroupBufferSize() int {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return groupBufferSize
+}
+
+// SetGroupBufferSize sets how much screen output (in bytes) a group buffers
+// before it's written (at the end of a line), 64KB by default, 0 or less
+// writes out each line as it ends (keeping just partial lines together)
+func SetGroupBufferSize(size int) {
+	mutex.Lock()
+	{
+		groupBufferSize = size
+	}
+	mutex.Unlock()
+}
+
+// setGroup sets (or with nil, removes) the open group of the goroutine,
+// groupMu must be held
+func setGroup(gid uint64, g *GroupScope) {
+	_, open := groups[gid]
+	switch {
+	case g == nil && open:
+		delete(groups, gid)
+		atomic.AddInt32(&groupGoroutines, -1)
+	case g != nil:
+		if !open {
+			atomic.AddInt32(&groupGoroutines, 1)
+		}
+		groups[gid] = g
+	}
+}
+
+// currentGroup returns the open group of the calling goroutine, nil if none
+// (cheap if no goroutine has an open group)
+func currentGroup() *GroupScope {
+	if atomic.LoadInt32(&groupGoroutines) == 0 {
+		return nil
+	}
+	return goroutineGroup(goroutineID())
+}
+
+// goroutineGroup returns the open group of the goroutine with the given id,
+// nil if none
+func goroutineGroup(gid uint64) *GroupScope {
+	groupMu.Lock()
+	defer groupMu.Unlock()
+	return groups[gid]
+}
+
+// flushGroups writes the buffered screen output of all open groups, eg:
+// before the program exits
+func flushGroups() {
+	if atomic.LoadInt32(&groupGoroutines) == 0 {
+		return
+	}
+	groupMu.Lock()
+	var open []*GroupScope
+	for _, g := range groups {
+		open = append(open, g)
+	}
+	groupMu.Unlock()
+	for _, g := range open {
+		g.Flush()
+	}
+}
+
+// groupPrefixLocked returns what goes in front of the output of the group,
+// the name for the log file (and the screen if tagged), the mutex must be
+// held
+func (g *GroupScope) groupPrefixLocked(outputTgt int) string {
+	if outputTgt&ForScreen != 0 && !g.tagged {
+		return ""
+	}
+	return "[" + g.name + "] "
+}
+
+// bufferLocked buffers screen output for the given writer, once the buffer
+// is full (and ends a line) it's flushed, after the group ended the output
+// is written right away, the mutex must be held
+func (g *GroupScope) bufferLocked(w io.Writer, b []byte) (int, error) {
+	if g.ended {
+		return screenWriteLocked(w, b)
+	}
+	if n := len(g.chunks); n != 0 && g.chunks[n-1].w == w {
+		g.chunks[n-1].b = append(g.chunks[n-1].b, b...)
+	} else {
+		g.chunks = append(g.chunks, groupChunk{w, append([]byte(nil), b...)})
+	}
+	g.size += len(b)
+	if g.size >= groupBufferSize && len(b) != 0 && b[len(b)-1] == '\n' {
+		g.flushLocked()
+	}
+	return len(b), nil
+}
+
+// flushLocked writes the buffered screen output, if the (ungrouped) screen
+// output is mid-line that line is ended first, the mutex must be held
+func (g *GroupScope) flushLocked() {
+	if len(g.chunks) == 0 {
+		return
+	}
+	if !screenNewline {
+		screenWriteLocked(g.chunks[0].w, []byte("\n"))
+	}
+	for _, chunk := range g.chunks {
+		screenWriteLocked(chunk.w, chunk.b)
+	}
+	last := g.chunks[len(g.chunks)-1].b
+	screenNewline = len(last) == 0 || last[len(last)-1] == '\n'
+	g.chunks = nil
+	g.size = 0
+}
@@ -0,0 +1,103 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package test for: out/group.go
+//   Testing in this file focuses on buffering grouped screen output, the per
+//   group newline tracking, the group tags and flushing at the size limit
+
+package out
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dvln/testify/assert"
+)
+
+func TestGroup(t *testing.T) {
+	screenBuf := new(bytes.Buffer)
+	logBuf := new(bytes.Buffer)
+	SetWriter(LevelAll, screenBuf, ForScreen)
+	SetWriter(LevelAll, logBuf, ForLogfile)
+	SetThreshold(LevelInfo, ForLogfile)
+	SetFlags(LevelAll, 0, ForLogfile)
+
+	step := make(chan bool)
+	done := make(chan bool)
+	go func() {
+		g := Group("job-1").Tagged()
+		Print("building ")
+		Issueln("first\nsecond")
+		Print("partial ")
+		step <- true
+		<-step
+		Println("rest")
+		g.End()
+		g.End()
+		Println("after")
+		done <- true
+	}()
+	<-step
+	// the group's output is buffered, ungrouped output isn't held up
+	Println("main")
+	assert.Equal(t, "main\n", screenBuf.String())
+	assert.Contains(t, logBuf.String(), "[job-1] building first\n[job-1] Issue: second\n")
+	step <- true
+	<-done
+	// ... and the group tracks its own newlines
+	assert.Equal(t, "main\n"+
+		"[job-1] building first\n"+
+		"[job-1] Issue: second\n"+
+		"[job-1] partial rest\n"+
+		"after\n", screenBuf.String())
+
+	// the buffer is flushed at the end of a line once full, untagged groups
+	// only have the name in front of the log file output
+	screenBuf.Reset()
+	logBuf.Reset()
+	SetGroupBufferSize(10)
+	SetOutputFormat(FormatLogfmt, ForLogfile)
+	g := Group("job-2")
+	Print("01234")
+	Print("56789")
+	assert.Equal(t, "", screenBuf.String())
+	Println()
+	assert.Equal(t, "0123456789\n", screenBuf.String())
+	assert.Contains(t, logBuf.String(), "group=job-2")
+	g.End()
+	assert.Equal(t, 0, len(groups))
+	ResetOutPkg()
+}
+
+func TestGroupJoin(t *testing.T) {
+	screenBuf := new(bytes.Buffer)
+	SetWriter(LevelAll, screenBuf, ForScreen)
+
+	g := Group("job-3").Tagged()
+	done := make(chan bool)
+	go func() {
+		defer func() { done <- true }()
+		defer g.Join()()
+		Println("helper")
+	}()
+	<-done
+	Println("task")
+	assert.Equal(t, "", screenBuf.String())
+	// a new group on the goroutine ends the previous one
+	g2 := Group("job-4")
+	assert.Equal(t, "[job-3] helper\n[job-3] task\n", screenBuf.String())
+	Print("unfinished")
+	g2.End()
+	assert.Equal(t, "[job-3] helper\n[job-3] task\nunfinished\n", screenBuf.String())
+	ResetOutPkg()
+}
@@ -0,0 +1,295 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package out
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// loggerKey is the context key of the Logger carried by a context
+type loggerKey struct{}
+
+// rootLogger is the Logger without a context prefix
+var rootLogger = &Logger{}
+
+// Logger outputs via the 'out' package with a context prefix in front of
+// each line (after the level prefix), see WithPrefix(), a Logger is safe
+// to use from multiple goroutines.  A Logger can also have its own exit and
+// clock funcs, see WithExitFunc() and WithClock(), and its output can be
+// must-show output at any level, see Always().
+type Logger struct {
+	prefix   string
+	mustShow bool
+	exitFunc func(exitVal int)
+	clock    func() time.Time
+}
+
+// WithPrefix returns a Logger that puts the given context prefix in front of
+// every line it outputs, after the level prefix and any error code, eg:
+//
+//	log := out.WithPrefix("[repo foo] ")
+//	log.Println("Syncing")         // "[repo foo] Syncing"
+//	log.Issueln("No such branch")  // "Issue: [repo foo] No such branch"
+//
+// The global level prefixes aren't changed, the thresholds, flags, writers
+// and such of the 'out' package all apply as usual.  Structured formats get
+// a "prefix" field instead.  See NewContext() to carry a Logger in a
+// context.Context.
+func WithPrefix(pfx string) *Logger {
+	return &Logger{prefix: pfx}
+}
+
+// WithPrefix returns a Logger with the given context prefix added after the
+// prefix of this Logger, ie: prefixes nest, eg: "[repo foo] [worker 7] ", the
+// Logger's exit and clock funcs (if any) are kept
+func (l *Logger) WithPrefix(pfx string) *Logger {
+	newLog := *l
+	newLog.prefix += pfx
+	return &newLog
+}
+
+// Always returns a Logger whose output, at any level, is must-show output,
+// ie: it is shown on the screen regardless of the screen threshold and quiet
+// mode (see SetQuiet()) but not in silent mode (see SetSilent()), eg:
+//
+//	out.SetQuiet(true)
+//	out.Noteln("Looking up flag1")          // not shown on the screen
+//	out.Always().Noteln("3 files updated")  // shown, as a Note
+func Always() *Logger {
+	return rootLogger.Always()
+}
+
+// Always returns a Logger with the context prefix, exit and clock funcs of
+// this Logger whose output is must-show output, see Always()
+func (l *Logger) Always() *Logger {
+	newLog := *l
+	newLog.mustShow = true
+	return &newLog
+}
+
+// WithExitFunc returns a Logger that exits via the given func instead of the
+// package exit func (see SetExitFunc()) when its Fatal*() routines are used,
+// eg: so parallel tests can each check their own exit value.  Pass in nil
+// to use the package exit func again.
+func (l *Logger) WithExitFunc(eFunc func(exitVal int)) *Logger {
+	newLog := *l
+	newLog.exitFunc = eFunc
+	return &newLog
+}
+
+// WithClock returns a Logger that gets the time for the date/time flag
+// metadata of its output via the given func instead of the package clock
+// (see SetClock()).  Pass in nil to use the package clock again.
+func (l *Logger) WithClock(cFunc func() time.Time) *Logger {
+	newLog := *l
+	newLog.clock = cFunc
+	return &newLog
+}
+
+// Prefix returns the context prefix of the Logger
+func (l *Logger) Prefix() string {
+	return l.prefix
+}
+
+// NewContext returns a copy of the context carrying the Logger, see
+// FromContext()
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the Logger carried by the context, if there isn't one
+// a Logger without a context prefix is returned so it's always usable, eg:
+//
+//	func sync(ctx context.Context, repo string) {
+//		ctx = out.ContextWithPrefix(ctx, "[repo "+repo+"] ")
+//		log := out.FromContext(ctx)
+//		log.Println("Syncing")
+//		...
+//	}
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*Logger); ok && l != nil {
+		return l
+	}
+	return rootLogger
+}
+
+// ContextWithPrefix returns a copy of the context carrying a Logger with the
+// given context prefix added after that of the Logger the context carries
+// (if any), see NewContext() and Logger.WithPrefix()
+func ContextWithPrefix(ctx context.Context, pfx string) context.Context {
+	return NewContext(ctx, FromContext(ctx).WithPrefix(pfx))
+}
+
+// opts returns the output options for the Logger's output
+func (l *Logger) opts() outputOpts {
+	return outputOpts{prefix: l.prefix, mustShow: l.mustShow, exitFunc: l.exitFunc, clock: l.clock}
+}
+
+// Trace is Trace() with the Logger's context prefix
+func (l *Logger) Trace(v ...interface{}) {
+	TRACE.output(false, 0, l.opts(), v...)
+}
+
+// Traceln is Traceln() with the Logger's context prefix
+func (l *Logger) Traceln(v ...interface{}) {
+	TRACE.outputln(false, 0, l.opts(), v...)
+}
+
+// Tracef is Tracef() with the Logger's context prefix
+func (l *Logger) Tracef(format string, v ...interface{}) {
+	TRACE.outputf(false, 0, l.opts(), format, v...)
+}
+
+// Debug is Debug() with the Logger's context prefix
+func (l *Logger) Debug(v ...interface{}) {
+	DEBUG.output(false, 0, l.opts(), v...)
+}
+
+// Debugln is Debugln() with the Logger's context prefix
+func (l *Logger) Debugln(v ...interface{}) {
+	DEBUG.outputln(false, 0, l.opts(), v...)
+}
+
+// Debugf is Debugf() with the Logger's context prefix
+func (l *Logger) Debugf(format string, v ...interface{}) {
+	DEBUG.outputf(false, 0, l.opts(), format, v...)
+}
+
+// Verbose is Verbose() with the Logger's context prefix
+func (l *Logger) Verbose(v ...interface{}) {
+	VERBOSE.output(false, 0, l.opts(), v...)
+}
+
+// Verboseln is Verboseln() with the Logger's context prefix
+func (l *Logger) Verboseln(v ...interface{}) {
+	VERBOSE.outputln(false, 0, l.opts(), v...)
+}
+
+// Verbosef is Verbosef() with the Logger's context prefix
+func (l *Logger) Verbosef(format string, v ...interface{}) {
+	VERBOSE.outputf(false, 0, l.opts(), format, v...)
+}
+
+// Print is Print() with the Logger's context prefix
+func (l *Logger) Print(v ...interface{}) {
+	INFO.output(false, 0, l.opts(), v...)
+}
+
+// Println is Println() with the Logger's context prefix
+func (l *Logger) Println(v ...interface{}) {
+	INFO.outputln(false, 0, l.opts(), v...)
+}
+
+// Printf is Printf() with the Logger's context prefix
+func (l *Logger) Printf(format string, v ...interface{}) {
+	INFO.outputf(false, 0, l.opts(), format, v...)
+}
+
+// MustShow is MustShow() with the Logger's context prefix
+func (l *Logger) MustShow(v ...interface{}) {
+	opts := l.opts()
+	opts.mustShow = true
+	INFO.output(false, 0, opts, v...)
+}
+
+// MustShowln is MustShowln() with the Logger's context prefix
+func (l *Logger) MustShowln(v ...interface{}) {
+	opts := l.opts()
+	opts.mustShow = true
+	INFO.outputln(false, 0, opts, v...)
+}
+
+// MustShowf is MustShowf() with the Logger's context prefix
+func (l *Logger) MustShowf(format string, v ...interface{}) {
+	opts := l.opts()
+	opts.mustShow = true
+	INFO.outputf(false, 0, opts, format, v...)
+}
+
+// Info is Info() with the Logger's context prefix
+func (l *Logger) Info(v ...interface{}) {
+	INFO.output(false, 0, l.opts(), v...)
+}
+
+// Infoln is Infoln() with the Logger's context prefix
+func (l *Logger) Infoln(v ...interface{}) {
+	INFO.outputln(false, 0, l.opts(), v...)
+}
+
+// Infof is Infof() with the Logger's context prefix
+func (l *Logger) Infof(format string, v ...interface{}) {
+	INFO.outputf(false, 0, l.opts(), format, v...)
+}
+
+// Note is Note() with the Logger's context prefix
+func (l *Logger) Note(v ...interface{}) {
+	NOTE.output(false, 0, l.opts(), v...)
+}
+
+// Noteln is Noteln() with the Logger's context prefix
+func (l *Logger) Noteln(v ...interface{}) {
+	NOTE.outputln(false, 0, l.opts(), v...)
+}
+
+// Notef is Notef() with the Logger's context prefix
+func (l *Logger) Notef(format string, v ...interface{}) {
+	NOTE.outputf(false, 0, l.opts(), format, v...)
+}
+
+// Issue is Issue() with the Logger's context prefix
+func (l *Logger) Issue(v ...interface{}) {
+	ISSUE.output(false, 0, l.opts(), v...)
+}
+
+// Issueln is Issueln() with the Logger's context prefix
+func (l *Logger) Issueln(v ...interface{}) {
+	ISSUE.outputln(false, 0, l.opts(), v...)
+}
+
+// Issuef is Issuef() with the Logger's context prefix
+func (l *Logger) Issuef(format string, v ...interface{}) {
+	ISSUE.outputf(false, 0, l.opts(), format, v...)
+}
+
+// Error is Error() with the Logger's context prefix
+func (l *Logger) Error(v ...interface{}) {
+	ERROR.output(false, 0, l.opts(), v...)
+}
+
+// Errorln is Errorln() with the Logger's context prefix
+func (l *Logger) Errorln(v ...interface{}) {
+	ERROR.outputln(false, 0, l.opts(), v...)
+}
+
+// Errorf is Errorf() with the Logger's context prefix
+func (l *Logger) Errorf(format string, v ...interface{}) {
+	ERROR.outputf(false, 0, l.opts(), format, v...)
+}
+
+// Fatal is Fatal() with the Logger's context prefix, the tool exits
+func (l *Logger) Fatal(v ...interface{}) {
+	FATAL.output(true, int(atomic.LoadInt32(&errorExitVal)), l.opts(), v...)
+}
+
+// Fatalln is Fatalln() with the Logger's context prefix, the tool exits
+func (l *Logger) Fatalln(v ...interface{}) {
+	FATAL.outputln(true, int(atomic.LoadInt32(&errorExitVal)), l.opts(), v...)
+}
+
+// Fatalf is Fatalf() with the Logger's context prefix, the tool exits
+func (l *Logger) Fatalf(format string, v ...interface{}) {
+	FATAL.outputf(true, int(atomic.LoadInt32(&errorExitVal)), l.opts(), format, v...)
+}
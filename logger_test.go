@@ -0,0 +1,116 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package test for: out/logger.go
+//   Testing in this file focuses on context prefixes, their nesting, their
+//   placement after the level prefix and error code, the context carrier and
+//   the Logger's own exit and clock funcs
+
+package out
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/dvln/testify/assert"
+)
+
+func TestWithPrefix(t *testing.T) {
+	screenBuf := new(bytes.Buffer)
+	logBuf := new(bytes.Buffer)
+	SetWriter(LevelAll, screenBuf, ForScreen)
+	SetWriter(LevelAll, logBuf, ForLogfile)
+	SetThreshold(LevelInfo, ForLogfile)
+	SetFlags(LevelAll, 0, ForScreen)
+	SetFlags(LevelAll, Lshortfile, ForLogfile)
+
+	log := WithPrefix("[repo foo] ")
+	log.Println("Syncing")
+	log.Issueln("No such branch\nor tag")
+	log.WithPrefix("[worker 7] ").Notef("%d files\n", 3)
+	Println("no prefix")
+	log.Issue(NewErr("bad ref", 293))
+	assert.Equal(t, "[repo foo] Syncing\n"+
+		"Issue: [repo foo] No such branch\n"+
+		"Issue: [repo foo] or tag\n"+
+		"Note: [repo foo] [worker 7] 3 files\n"+
+		"no prefix\n"+
+		"Issue #293: [repo foo] bad ref", screenBuf.String())
+	// the metadata points at the caller
+	assert.Equal(t, 6, bytes.Count(logBuf.Bytes(), []byte("logger_test.go:")))
+	assert.Equal(t, "[repo foo] [worker 7] ", log.WithPrefix("[worker 7] ").Prefix())
+
+	// structured formats get a prefix field
+	logBuf.Reset()
+	SetOutputFormat(FormatLogfmt, ForLogfile)
+	log.Println("Syncing")
+	assert.Contains(t, logBuf.String(), `prefix="[repo foo]"`)
+	assert.Contains(t, logBuf.String(), `file=logger_test.go`)
+	ResetOutPkg()
+}
+
+func TestLoggerContext(t *testing.T) {
+	screenBuf := new(bytes.Buffer)
+	SetWriter(LevelAll, screenBuf, ForScreen)
+
+	ctx := context.Background()
+	FromContext(ctx).Println("plain")
+	ctx = ContextWithPrefix(ctx, "[repo foo] ")
+	ctx = ContextWithPrefix(ctx, "[worker 7] ")
+	FromContext(ctx).Println("nested")
+	ctx = NewContext(ctx, WithPrefix("[other] "))
+	FromContext(ctx).Println("replaced")
+	assert.Equal(t, "plain\n"+
+		"[repo foo] [worker 7] nested\n"+
+		"[other] replaced\n", screenBuf.String())
+	ResetOutPkg()
+}
+
+func TestLoggerHooks(t *testing.T) {
+	logBuf := new(bytes.Buffer)
+	SetWriter(LevelAll, ioutil.Discard, ForScreen)
+	SetWriter(LevelAll, logBuf, ForLogfile)
+	SetThreshold(LevelInfo, ForLogfile)
+	SetFlags(LevelAll, Ldate|Ltime, ForLogfile)
+	SetErrorExitVal(5)
+	pkgExited := false
+	SetExitFunc(func(exitVal int) { pkgExited = true })
+
+	// each Logger exits and gets the time via its own funcs, even when used
+	// by parallel tests
+	t.Run("group", func(t *testing.T) {
+		for _, day := range []int{3, 4} {
+			day := day
+			t.Run(fmt.Sprint(day), func(t *testing.T) {
+				t.Parallel()
+				exitVal := 0
+				stamp := time.Date(2016, 3, day, 5, 6, 7, 0, time.Local)
+				log := WithPrefix(fmt.Sprintf("[day %d] ", day)).
+					WithExitFunc(func(val int) { exitVal = val }).
+					WithClock(func() time.Time { return stamp })
+				log.WithPrefix("[sub] ").Println("started")
+				log.Fatalln("giving up")
+				assert.Equal(t, 5, exitVal)
+			})
+		}
+	})
+	SetExitFunc(nil)
+	assert.Equal(t, false, pkgExited)
+	assert.Contains(t, logBuf.String(), "2016/03/03 05:06:07 [day 3] [sub] started\n")
+	assert.Contains(t, logBuf.String(), "2016/03/04 05:06:07 Fatal: [day 4] giving up\n")
+	ResetOutPkg()
+}
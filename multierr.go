@@ -0,0 +1,355 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package out
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// These identify which stack traces are shown when a MultiError is dumped
+// with a stack trace (eg: via Fatal() or DefaultError() with stack traces
+// requested), see SetStackPolicy() on the MultiError
+const (
+	MultiStackFirst = iota // stack trace of the 1st detailed child error only
+	MultiStackAll          // stack traces of every detailed child error
+	MultiStackNone         // just the stack where the MultiError was created
+)
+
+// MultiError aggregates multiple errors into one, eg: batch operations that
+// keep going on failure and want to report all failures at the end.  It
+// implements the DetailedError interface so it can be passed into Issue(),
+// Error() or Fatal() (and friends) like any other detailed error:
+// - the code is the highest code of the child errors (see Code())
+// - the output level is the most severe level of the child errors
+// - each child error is shown with its own level prefix and error code
+// - errors.Is() and errors.As() check all of the child errors
+// Use NewMultiErr() to create one, Append() to add errors (nil errors are
+// ignored) and ErrOrNil() to return it as an error only if errors occurred:
+//
+//	multiErr := out.NewMultiErr()
+//	for _, repo := range repos {
+//		multiErr.Append(syncRepo(repo))
+//	}
+//	return multiErr.ErrOrNil()
+type MultiError struct {
+	mu          sync.RWMutex
+	errs        []error
+	stack       string
+	context     string
+	lvlOut      *LvlOutput
+	fields      []ErrField
+	stackPolicy int
+}
+
+// NewMultiErr returns a new MultiError containing any non-nil errors given
+// along with the current stack trace (used if the MultiStackNone policy is set)
+func NewMultiErr(errs ...error) *MultiError {
+	stack, context := stackTrace(2)
+	m := &MultiError{stack: stack, context: context}
+	return m.Append(errs...)
+}
+
+// Append adds the given errors to the MultiError, nil errors are skipped so
+// one can blindly append the results of calls, returns the MultiError
+func (m *MultiError) Append(errs ...error) *MultiError {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, err := range errs {
+		if err != nil {
+			m.errs = append(m.errs, err)
+		}
+	}
+	return m
+}
+
+// Errors returns a copy of the list of errors that have been aggregated
+func (m *MultiError) Errors() []error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	errs := make([]error, len(m.errs))
+	copy(errs, m.errs)
+	return errs
+}
+
+// Len returns the number of errors that have been aggregated
+func (m *MultiError) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.errs)
+}
+
+// ErrOrNil returns nil if no errors have been aggregated, otherwise it returns
+// the MultiError itself (avoids the non-nil interface holding a nil ptr trap)
+func (m *MultiError) ErrOrNil() error {
+	if m == nil || m.Len() == 0 {
+		return nil
+	}
+	return m
+}
+
+// Unwrap gives errors.Is() and errors.As() access to all the child errors
+func (m *MultiError) Unwrap() []error {
+	return m.Errors()
+}
+
+// StackPolicy returns the current stack trace policy, see SetStackPolicy()
+func (m *MultiError) StackPolicy() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.stackPolicy
+}
+
+// SetStackPolicy identifies which stack traces are shown when the error is
+// dumped with stack traces: MultiStackFirst (the default) to show the stack
+// of the 1st child detailed error, MultiStackAll to show the stack for each
+// child detailed error or MultiStackNone for only the stack where the
+// MultiError was created.  Returns the MultiError for chaining.
+func (m *MultiError) SetStackPolicy(policy int) *MultiError {
+	m.mu.Lock()
+	m.stackPolicy = policy
+	m.mu.Unlock()
+	return m
+}
+
+// Error implements the Go error interface, each child error is shown on
+// subsequent (indented) lines with its own level prefix and error code
+func (m *MultiError) Error() string {
+	stackTrace := false
+	shallow := false
+	prefix := false
+	return DefaultError(m, stackTrace, shallow, prefix)
+}
+
+// Message returns the summary line for the MultiError (eg: "3 errors
+// occurred:"), the child error messages are not included, see Error()
+func (m *MultiError) Message() string {
+	count := m.Len()
+	if count == 1 {
+		return "1 error occurred:"
+	}
+	return fmt.Sprintf("%d errors occurred:", count)
+}
+
+// Stack returns the stack trace based on the stack policy, see SetStackPolicy()
+func (m *MultiError) Stack() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.stackPolicy == MultiStackNone {
+		return m.stack
+	}
+	var stacks []string
+	for idx, err := range m.errs {
+		if _, ok := err.(DetailedError); !ok {
+			continue
+		}
+		var errLines []string
+		var origStack string
+		fillErrorInfo(err, true, &errLines, &origStack)
+		if m.stackPolicy == MultiStackFirst {
+			return origStack
+		}
+		stacks = append(stacks, fmt.Sprintf("[%d of %d] %s", idx+1, len(m.errs), origStack))
+	}
+	if stacks == nil {
+		return m.stack
+	}
+	return strings.Join(stacks, "\n")
+}
+
+// Context returns the stack trace context from where the MultiError was created
+func (m *MultiError) Context() string {
+	return m.context
+}
+
+// Code returns the highest error code of all the child errors, if none of
+// them have a code set then the default error code is returned
+func (m *MultiError) Code() int {
+	code := 0
+	for _, err := range m.Errors() {
+		childCode := Code(err)
+		if childCode != int(defaultErrCode) && childCode > code {
+			code = childCode
+		}
+	}
+	if code == 0 {
+		code = int(defaultErrCode)
+	}
+	return code
+}
+
+// Inner returns nil, a MultiError has child errors but doesn't wrap a single
+// error, see Errors() (or Unwrap() via errors.Is() and errors.As())
+func (m *MultiError) Inner() error {
+	return nil
+}
+
+// LvlOut returns the most severe output level of the MultiError itself and
+// all child detailed errors (child errors that aren't detailed count as ERROR)
+func (m *MultiError) LvlOut() *LvlOutput {
+	m.mu.RLock()
+	lvlOut := m.lvlOut
+	errs := m.errs
+	m.mu.RUnlock()
+	if lvlOut == nil {
+		lvlOut = ERROR
+	}
+	for _, err := range errs {
+		if detErr, ok := err.(DetailedError); ok {
+			if childLvlOut := detErr.LvlOut(); childLvlOut != nil && childLvlOut.level > lvlOut.level {
+				lvlOut = childLvlOut
+			}
+		}
+	}
+	return lvlOut
+}
+
+// SetLvlOut sets the MultiError output level, as with BaseError's the level
+// must be ISSUE, ERROR or FATAL otherwise ERROR is used.  Note that child
+// errors with a more severe level still win, see LvlOut().
+func (m *MultiError) SetLvlOut(lvlOut *LvlOutput) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if lvlOut.level < LevelIssue {
+		m.lvlOut = ERROR
+	} else {
+		m.lvlOut = lvlOut
+	}
+}
+
+// Fields returns the key/value context attached to the MultiError itself,
+// child error fields are shown with the child errors
+func (m *MultiError) Fields() []ErrField {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.fields
+}
+
+// With attaches key/value context to the MultiError, as it collects errors
+// it is changed in place (unlike BaseError With()), it's returned for chaining
+func (m *MultiError) With(keyvals ...interface{}) DetailedError {
+	m.mu.Lock()
+	m.fields = appendFields(m.fields, keyvals...)
+	m.mu.Unlock()
+	return m
+}
+
+// MarshalJSON implements json.Marshaler, child errors are stored in the
+// "errors" list (see BaseError MarshalJSON() for details)
+func (m *MultiError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newJSONError(m))
+}
+
+// UnmarshalJSON implements json.Unmarshaler, rebuilding a MultiError that
+// was serialized via MarshalJSON()
+func (m *MultiError) UnmarshalJSON(data []byte) error {
+	var jErr jsonError
+	if err := json.Unmarshal(data, &jErr); err != nil {
+		return err
+	}
+	if jErr.Errors == nil {
+		jErr.Errors = []*jsonError{}
+	}
+	err, convErr := jErr.toError()
+	if convErr != nil {
+		return convErr
+	}
+	newMulti := err.(*MultiError)
+	m.mu.Lock()
+	m.errs = newMulti.errs
+	m.stack = newMulti.stack
+	m.context = newMulti.context
+	m.fields = newMulti.fields
+	m.lvlOut = newMulti.lvlOut
+	m.stackPolicy = newMulti.stackPolicy
+	m.mu.Unlock()
+	return nil
+}
+
+// defaultError is the DefaultError() implementation for MultiError's, the
+// summary line, fields and stack trace get the MultiError level prefix (if
+// requested) while each child error is indented and prefixed with its own
+// level prefix and error code, eg:
+//
+//	Error #305: 2 errors occurred:
+//	  Error #201: unable to clone repo foo
+//	  Issue #305: unknown repo bar
+//
+// Without the level prefixes (eg: via Error(), as used when the MultiError
+// is dumped via Issue() and friends which add their own prefix) each child
+// error just has its error code (if set) in front of it, eg:
+//
+//	2 errors occurred:
+//	  #201: unable to clone repo foo
+//	  #305: unknown repo bar
+//
+// The MultiError's own fields line is left out if withFields is false.
+func (m *MultiError) defaultError(withStackTrace, shallow, outLvlPfx, withFields bool) string {
+	var pfx string
+	errCode := int(defaultErrCode)
+	if outLvlPfx {
+		pfx = m.LvlOut().prefix
+		errCode = Code(m)
+	}
+	lines := []string{InsertPrefix(m.Message(), pfx, AlwaysInsert, errCode)}
+	for _, err := range m.Errors() {
+		var childStr string
+		detErr, ok := err.(DetailedError)
+		switch {
+		case ok && outLvlPfx:
+			childStr = DefaultError(detErr, false, shallow, true)
+		case ok:
+			childStr = DefaultError(detErr, false, shallow, false)
+			if code := Code(detErr); code != 0 && code != int(defaultErrCode) {
+				childStr = InsertPrefix(childStr, fmt.Sprintf("#%d: ", code), BlankContinue, 0)
+			}
+		case outLvlPfx:
+			childStr = InsertPrefix(err.Error(), ERROR.prefix, AlwaysInsert, 0)
+		default:
+			childStr = err.Error()
+		}
+		lines = append(lines, InsertPrefix(childStr, "  ", AlwaysInsert, 0))
+	}
+	var trailer []string
+	if fields := m.Fields(); withFields && len(fields) != 0 {
+		trailer = append(trailer, "Fields: "+fieldsString(fields))
+	}
+	if withStackTrace {
+		trailer = append(trailer, "", "Stack Trace: "+m.Stack())
+	}
+	if trailer != nil {
+		lines = append(lines, InsertPrefix(strings.Join(trailer, "\n"), pfx, AlwaysInsert, errCode))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// getDetailedError returns any DetailedError found in the list of interfaces
+// given, if more than one is found they are aggregated into a MultiError so
+// none are silently ignored (nil is returned if none are found)
+func getDetailedError(v ...interface{}) DetailedError {
+	detErrs := getAnyDetailedErrors(v...)
+	switch len(detErrs) {
+	case 0:
+		return nil
+	case 1:
+		return detErrs[0]
+	}
+	multiErr := &MultiError{}
+	for _, detErr := range detErrs {
+		multiErr.Append(detErr)
+	}
+	return multiErr
+}
@@ -0,0 +1,137 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package test for: out/multierr.go
+//   This file focuses on testing the aggregated (multi) error support.
+
+package out
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/dvln/testify/assert"
+)
+
+func TestMultiError(t *testing.T) {
+	multiErr := NewMultiErr(nil)
+	if multiErr.ErrOrNil() != nil {
+		t.Error("an empty multi error should give a nil error from ErrOrNil()")
+	}
+	cloneErr := NewErr("unable to clone repo foo", 201)
+	readErr := WrapErr(io.EOF, "short read for repo bar", 305)
+	readErr.SetLvlOut(ISSUE)
+	multiErr.Append(cloneErr, nil, readErr, fmt.Errorf("plain failure"))
+
+	assert.Equal(t, multiErr.Len(), 3)
+	assert.Equal(t, multiErr.Code(), 305)
+	assert.Equal(t, Code(multiErr), 305)
+	assert.Equal(t, multiErr.LvlOut(), ERROR)
+	readErr.SetLvlOut(FATAL)
+	assert.Equal(t, multiErr.LvlOut(), FATAL)
+	readErr.SetLvlOut(ISSUE)
+
+	if !errors.Is(multiErr, io.EOF) {
+		t.Error("errors.Is() should find io.EOF in the multi error children")
+	}
+	var baseErr *BaseError
+	if !errors.As(multiErr, &baseErr) || baseErr != cloneErr {
+		t.Error("errors.As() should find the 1st BaseError in the multi error children")
+	}
+
+	errStr := DefaultError(multiErr, false, false, true)
+	assert.Contains(t, errStr, "Error #305: 3 errors occurred:\n")
+	assert.Contains(t, errStr, "\n  Error #201: unable to clone repo foo\n")
+	assert.Contains(t, errStr, "\n  Issue #305: short read for repo bar\n  Issue #305: EOF\n")
+	assert.Contains(t, errStr, "\n  Error: plain failure")
+	assert.NotContains(t, errStr, "Stack Trace:")
+
+	// The default stack policy shows only the 1st child errors stack, then
+	// try out showing all of them
+	errStr = DefaultError(multiErr, true, false, false)
+	assert.Equal(t, strings.Count(errStr, "[running]:"), 1)
+	assert.Contains(t, errStr, "out.TestMultiError")
+	multiErr.SetStackPolicy(MultiStackAll)
+	errStr = DefaultError(multiErr, true, false, false)
+	assert.Contains(t, errStr, "Stack Trace: [1 of 3] goroutine ")
+	assert.Contains(t, errStr, "[2 of 3] goroutine ")
+	multiErr.SetStackPolicy(MultiStackNone)
+	assert.Equal(t, multiErr.Stack(), multiErr.stack)
+
+	// the codes and root errors of the children can be matched
+	if !IsError(multiErr, nil, 201) || !IsError(multiErr, io.EOF) || IsError(multiErr, nil, 999) {
+		t.Error("IsError() should check the codes and root errors of the multi error children")
+	}
+	// ... also when the multi error is wrapped
+	wrappedErr := fmt.Errorf("sync failed: %w", WrapErr(multiErr, "unable to sync", 600))
+	if !IsError(wrappedErr, nil, 201) || !IsError(wrappedErr, io.EOF) || IsError(wrappedErr, nil, 999) {
+		t.Error("IsError() should check the children of a wrapped multi error")
+	}
+
+	// when dumped each child just has its code, the level prefix is only
+	// added by the output level used
+	screenBuf := new(bytes.Buffer)
+	SetWriter(LevelAll, screenBuf, ForScreen)
+	SetFlags(LevelAll, 0, ForScreen)
+	Issueln(multiErr)
+	ResetOutPkg()
+	assert.Equal(t, "Issue #305: 3 errors occurred:\n"+
+		"Issue #305:   #201: unable to clone repo foo\n"+
+		"Issue #305:   #305: short read for repo bar\n"+
+		"Issue #305:         EOF\n"+
+		"Issue #305:   plain failure\n", screenBuf.String())
+	readErr.SetLvlOut(ISSUE)
+
+	multiErr.SetLvlOut(FATAL)
+	data, err := json.Marshal(multiErr)
+	if err != nil {
+		t.Fatalf("failed to marshal multi error: %v", err)
+	}
+	var newMultiErr MultiError
+	if err = json.Unmarshal(data, &newMultiErr); err != nil {
+		t.Fatalf("failed to unmarshal multi error: %v\n%s", err, data)
+	}
+	assert.Equal(t, newMultiErr.Len(), 3)
+	assert.Equal(t, newMultiErr.Code(), 305)
+	assert.Equal(t, newMultiErr.LvlOut(), FATAL)
+	assert.Equal(t, newMultiErr.StackPolicy(), MultiStackNone)
+	if !IsError(newMultiErr.Errors()[1], io.EOF, 305) {
+		t.Errorf("multi error child lost its code or root error in JSON:\n%s", data)
+	}
+}
+
+func TestMultipleDetailedErrorArgs(t *testing.T) {
+	firstErr := NewErr("first failure", 201)
+	secondErr := NewErr("second failure", 450)
+
+	screenBuf := new(bytes.Buffer)
+	SetWriter(LevelAll, screenBuf, ForScreen)
+	SetFlags(LevelAll, 0, ForScreen)
+	Errorln(firstErr, secondErr)
+	// each of the errors picks up the output level used
+	Issue(firstErr, secondErr)
+
+	// Now reset the most common things for the 'out' pkg so the next test
+	// func will operate sanely as if we're coming in fresh
+	ResetOutPkg()
+
+	// both errors are used (vs just the 1st) so the highest code wins
+	assert.Contains(t, screenBuf.String(), "Error #450: first failure second failure\n")
+	assert.Equal(t, ISSUE, firstErr.LvlOut())
+	assert.Equal(t, ISSUE, secondErr.LvlOut())
+}
@@ -101,7 +101,8 @@
 // to control tool output verbosity, ie: "-Dv" (both) is the "output everything"
 // mode via the Trace level, just "-D" is the Debug level and all levels below,
 // only "-v" sets the Verbose level and all levels below and Info/Print is the
-// default level with none of those options.
+// default level with none of those options.  RegisterFlags() adds these (and
+// a few log file related options) to a flag.FlagSet for you.
 //
 // Quick Plug: I like spf13's viper&cobra pkgs for CLI and config file mgmt
 package out
@@ -206,6 +207,7 @@ const (
 	SmartInsert               // Output context "til now" decides if prefix used
 	BlankInsert               // Only spaces inserted (same length as prefix)
 	SkipFirstLine             // 1st line in multi-line string has no prefix
+	BlankContinue             // Prefix the 1st line, blanks on the rest
 )
 
 // Level type is just an int, see related const enum with LevelTrace, ..
@@ -240,41 +242,47 @@ type LvlOutput struct {
 	logfileHndl io.Writer    // io.Writer for "logfile" output
 	logFlags    int          // flags: additional metadata on logfile output
 	formatter   Formatter    // optional output formatting extension/plugin
+	prefixColor string       // color of the prefix on the screen (ANSI SGR)
+	metaColor   string       // color of flag metadata on the screen (ANSI SGR)
+	wrap        bool         // soft wrap screen output to the screen width
 }
 
 // FlagMetadata stores the various log add-on fields that a client can request
 // such as a timestamp, the log level, the package, routine and line number
 // information, pid, etc
 type FlagMetadata struct {
-	Time   *time.Time `json:"time,omitempty"`
-	Path   string     `json:"path,omitempty"`
-	File   string     `json:"file,omitempty"`
-	Func   string     `json:"func,omitempty"`
-	LineNo int        `json:"lineno,omitempty"`
-	Level  string     `json:"level,omitempty"`
-	PID    int        `json:"pid,omitempty"`
-	Stack  string     `json:"stack,omitempty"`
+	Time        *time.Time             `json:"time,omitempty"`
+	Path        string                 `json:"path,omitempty"`
+	File        string                 `json:"file,omitempty"`
+	Func        string                 `json:"func,omitempty"`
+	LineNo      int                    `json:"lineno,omitempty"`
+	Level       string                 `json:"level,omitempty"`
+	PID         int                    `json:"pid,omitempty"`
+	Stack       string                 `json:"stack,omitempty"`
+	Fields      map[string]interface{} `json:"fields,omitempty"`
+	Hint        string                 `json:"hint,omitempty"`
+	Remediation string                 `json:"remediation,omitempty"`
 }
 
 var (
 	// Set up each output level, ie: level, prefix, screen/log hndl, flags, ...
 
 	// TRACE can be used as an io.Writer for trace level output
-	TRACE = &LvlOutput{level: LevelTrace, prefix: "Trace: ", screenHndl: os.Stdout, screenFlags: LscreenFlags, logfileHndl: ioutil.Discard, logFlags: LlogfileFlags}
+	TRACE = &LvlOutput{level: LevelTrace, prefix: "Trace: ", screenHndl: os.Stdout, screenFlags: LscreenFlags, logfileHndl: ioutil.Discard, logFlags: LlogfileFlags, prefixColor: "90", metaColor: "90"}
 	// DEBUG can be used as an io.Writer for debug level output
-	DEBUG = &LvlOutput{level: LevelDebug, prefix: "Debug: ", screenHndl: os.Stdout, screenFlags: LscreenFlags, logfileHndl: ioutil.Discard, logFlags: LlogfileFlags}
+	DEBUG = &LvlOutput{level: LevelDebug, prefix: "Debug: ", screenHndl: os.Stdout, screenFlags: LscreenFlags, logfileHndl: ioutil.Discard, logFlags: LlogfileFlags, prefixColor: "36", metaColor: "90"}
 	// VERBOSE can be used as an io.Writer for verbose level output
-	VERBOSE = &LvlOutput{level: LevelVerbose, prefix: "", screenHndl: os.Stdout, screenFlags: 0, logfileHndl: ioutil.Discard, logFlags: LlogfileFlags}
+	VERBOSE = &LvlOutput{level: LevelVerbose, prefix: "", screenHndl: os.Stdout, screenFlags: 0, logfileHndl: ioutil.Discard, logFlags: LlogfileFlags, prefixColor: "", metaColor: "90"}
 	// INFO can be used as an io.Writer for info|print level output
-	INFO = &LvlOutput{level: LevelInfo, prefix: "", screenHndl: os.Stdout, screenFlags: 0, logfileHndl: ioutil.Discard, logFlags: LlogfileFlags}
+	INFO = &LvlOutput{level: LevelInfo, prefix: "", screenHndl: os.Stdout, screenFlags: 0, logfileHndl: ioutil.Discard, logFlags: LlogfileFlags, prefixColor: "", metaColor: "90"}
 	// NOTE can be used as an io.Writer for note level output
-	NOTE = &LvlOutput{level: LevelNote, prefix: "Note: ", screenHndl: os.Stdout, screenFlags: 0, logfileHndl: ioutil.Discard, logFlags: LlogfileFlags}
+	NOTE = &LvlOutput{level: LevelNote, prefix: "Note: ", screenHndl: os.Stdout, screenFlags: 0, logfileHndl: ioutil.Discard, logFlags: LlogfileFlags, prefixColor: "32", metaColor: "90"}
 	// ISSUE can be used as an io.Writer for issue level output
-	ISSUE = &LvlOutput{level: LevelIssue, prefix: "Issue: ", screenHndl: os.Stdout, screenFlags: 0, logfileHndl: ioutil.Discard, logFlags: LlogfileFlags}
+	ISSUE = &LvlOutput{level: LevelIssue, prefix: "Issue: ", screenHndl: os.Stdout, screenFlags: 0, logfileHndl: ioutil.Discard, logFlags: LlogfileFlags, prefixColor: "33", metaColor: "90"}
 	// ERROR can be used as an io.Writer for error level output
-	ERROR = &LvlOutput{level: LevelError, prefix: "Error: ", screenHndl: os.Stderr, screenFlags: 0, logfileHndl: ioutil.Discard, logFlags: LlogfileFlags}
+	ERROR = &LvlOutput{level: LevelError, prefix: "Error: ", screenHndl: os.Stderr, screenFlags: 0, logfileHndl: ioutil.Discard, logFlags: LlogfileFlags, prefixColor: "31", metaColor: "90"}
 	// FATAL can be used as an io.Writer for fatal level output
-	FATAL = &LvlOutput{level: LevelFatal, prefix: "Fatal: ", screenHndl: os.Stderr, screenFlags: 0, logfileHndl: ioutil.Discard, logFlags: LlogfileFlags}
+	FATAL = &LvlOutput{level: LevelFatal, prefix: "Fatal: ", screenHndl: os.Stderr, screenFlags: 0, logfileHndl: ioutil.Discard, logFlags: LlogfileFlags, prefixColor: "1;31", metaColor: "90"}
 
 	// Set up all the LvlOutput level details in one array (except discard),
 	// the idea that one can control these pretty flexibly (if needed)
@@ -305,6 +313,24 @@ var (
 	// ErrorExit or IssueExit).  See SetStackTraceConfig() to change.
 	stackTraceConfig = StackTraceExitToLogfile
 
+	// screenFormat and logfileFormat are the output formats of the screen and
+	// logfile targets, "" is the normal text format (see SetOutputFormat())
+	screenFormat  string
+	logfileFormat string
+
+	// debugScope restricts trace and debug output to the packages or funcs
+	// matching one of its comma separated entries, see SetDebugScope()
+	debugScope string
+
+	// quiet and silent are the screen output modes, see SetQuiet() and
+	// SetSilent(), the logfile output isn't affected by them
+	quiet  bool
+	silent bool
+
+	// levelCounts counts the messages sent to each output level (whether or
+	// not the thresholds let them through), see Count()
+	levelCounts [LevelDiscard]uint64
+
 	// The below "<..>NameLength" flags help to aligh the output when dumping
 	// filenames, line #'s' and function names to a log file in front of the
 	// tools normal output.  This is weak (at best), but usually works "ok"
@@ -356,6 +382,16 @@ var (
 	// a temp output logfile name so it's visible at the end of a run, etc),
 	// See DeferFunc() and SetDeferFunc() to get and set this if desired.
 	deferFunc func(exitVal int)
+
+	// exitFunc is called to exit the process once any deferred func has been
+	// run, if nil os.Exit() is used (see SetExitFunc() to change it)
+	exitFunc func(exitVal int)
+
+	// clock is used to get the current time for date/time flag metadata, if
+	// nil time.Now() is used (see SetClock() to change it), it's guarded by
+	// clockMu (not the mutex) so now() can also be used with the mutex held
+	clock   func() time.Time
+	clockMu sync.RWMutex
 )
 
 // levelCheck insures valid log level "values" are provided
@@ -370,6 +406,17 @@ func levelCheck(level Level) Level {
 	}
 }
 
+// levelOutputter returns the LvlOutput of the given level, nil if none (eg:
+// for LevelDiscard)
+func levelOutputter(level Level) *LvlOutput {
+	for _, o := range outputters {
+		if o.level == level {
+			return o
+		}
+	}
+	return nil
+}
+
 // DeferFunc returns a function type (reference type) if a defer func has
 // been set, see SetDeferFunc(), otherwise nil.  A defer function is one that
 // is fired right before os.Exit() is called by the'out' package.
@@ -391,6 +438,62 @@ func SetDeferFunc(dFunc func(exitVal int)) {
 	mutex.Unlock()
 }
 
+// ExitFunc returns the function currently used to exit the process after any
+// deferred func has been run, nil if the default os.Exit() is used (see the
+// SetExitFunc() routine)
+func ExitFunc() func(exitVal int) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return exitFunc
+}
+
+// SetExitFunc sets the function used to exit the process (os.Exit() by default),
+// typically used in tests to capture the exit value instead of exiting.  Note
+// that if the given func returns then the 'out' package returns as well and
+// the caller keeps on running.  Pass in nil to restore the os.Exit() default.
+// The exit func is package-wide, a Logger can have its own instead (see the
+// Logger WithExitFunc() routine), eg: for tests that run in parallel.
+func SetExitFunc(eFunc func(exitVal int)) {
+	mutex.Lock()
+	{
+		exitFunc = eFunc
+	}
+	mutex.Unlock()
+}
+
+// Clock returns the function currently used to get the time for date/time
+// flag metadata and such, nil if the default time.Now() is used (see the
+// SetClock() routine)
+func Clock() func() time.Time {
+	clockMu.RLock()
+	defer clockMu.RUnlock()
+	return clock
+}
+
+// SetClock sets the function used to get the current time for date/time flag
+// metadata (time.Now() by default), typically used to freeze the time in tests
+// so output can be compared exactly.  Pass in nil to restore the default.
+// The clock is package-wide, a Logger can have its own instead (see the
+// Logger WithClock() routine).
+func SetClock(cFunc func() time.Time) {
+	clockMu.Lock()
+	{
+		clock = cFunc
+	}
+	clockMu.Unlock()
+}
+
+// now returns the current time via the clock func, see SetClock()
+func now() time.Time {
+	clockMu.RLock()
+	cFunc := clock
+	clockMu.RUnlock()
+	if cFunc == nil {
+		return time.Now()
+	}
+	return cFunc()
+}
+
 // Threshold returns the current screen or logfile output threshold level
 // depending upon which is requested, either out.ForScreen or out.ForLogfile
 func Threshold(outputTgt int) Level {
@@ -425,6 +528,62 @@ func SetThreshold(level Level, outputTgt int) {
 	}
 }
 
+// Quiet returns true if quiet mode is on, see SetQuiet()
+func Quiet() bool {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return quiet
+}
+
+// SetQuiet turns quiet mode on or off, in quiet mode only Issue, Error and
+// Fatal output (and must-show output, see MustShow()) is shown on the screen
+// regardless of the screen threshold, the log file output isn't affected.
+// This is meant for a tool's "-q" option, the summary lines a tool must
+// always print should use MustShow() and friends (Info level) or Always()
+// for must-show output at any other level.
+func SetQuiet(on bool) {
+	mutex.Lock()
+	{
+		quiet = on
+	}
+	mutex.Unlock()
+}
+
+// Silent returns true if silent mode is on, see SetSilent()
+func Silent() bool {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return silent
+}
+
+// SetSilent turns silent mode on or off, in silent mode only Fatal output is
+// shown on the screen (not even must-show output), the log file output isn't
+// affected.  Silent mode wins over quiet mode if both are on.
+func SetSilent(on bool) {
+	mutex.Lock()
+	{
+		silent = on
+	}
+	mutex.Unlock()
+}
+
+// screenWanted decides if output at the given level goes to the screen based
+// on the screen threshold and the quiet and silent modes, must-show output
+// ignores the threshold and quiet mode (but not silent mode)
+func screenWanted(level Level, threshold Level, quiet bool, silent bool, mustShow bool) bool {
+	switch {
+	case level == LevelDiscard:
+		return false
+	case silent:
+		return level == LevelFatal
+	case mustShow:
+		return true
+	case quiet && level < LevelIssue:
+		return false
+	}
+	return level >= threshold
+}
+
 // ShortFileNameLength returns the current "assumed" padding around short
 // file names within the "padded" flags output.  If you don't like the
 // default adjust via SetShortFileNameLength()
@@ -536,6 +695,35 @@ func (l Level) String() string {
 // LevelString2Level takes the string representation of a level and turns
 // it back into a Level type (integer type/iota)
 func LevelString2Level(s string) Level {
+	level, ok := levelFromString(s)
+	if !ok {
+		Fatalln("Invalid string level:", s, ", unable to map to Level type")
+	}
+	return level
+}
+
+// ParseLevel maps a level name (case insensitive, eg: "debug" or "DEBUG") to
+// its Level, "print" is accepted for the info level and "quiet" and "off" for
+// the discard level, an error is returned if the name isn't a known level
+func ParseLevel(s string) (Level, error) {
+	name := strings.ToUpper(strings.TrimSpace(s))
+	switch name {
+	case "PRINT":
+		return LevelInfo, nil
+	case "QUIET", "OFF":
+		return LevelDiscard, nil
+	}
+	level, ok := levelFromString(name)
+	if !ok {
+		return LevelInfo, fmt.Errorf("unknown level %q (valid: trace, debug, verbose, info, note, issue, error, fatal, discard)", s)
+	}
+	return level, nil
+}
+
+// levelFromString is the non-fatal version of LevelString2Level(), it will
+// map the string representation of a level back to a Level type and returns
+// false if the string isn't a known level
+func levelFromString(s string) (Level, bool) {
 	string2Lvl := map[string]Level{
 		"TRACE":   LevelTrace,
 		"DEBUG":   LevelDebug,
@@ -547,10 +735,8 @@ func LevelString2Level(s string) Level {
 		"FATAL":   LevelFatal,
 		"DISCARD": LevelDiscard,
 	}
-	if _, ok := string2Lvl[s]; !ok {
-		Fatalln("Invalid string level:", s, ", unable to map to Level type")
-	}
-	return string2Lvl[s]
+	level, ok := string2Lvl[s]
+	return level, ok
 }
 
 // Prefix returns the current prefix for the given log level
@@ -675,6 +861,7 @@ func Writer(level Level, outputTgt int) io.Writer {
 // SetWriter sets the screen and/or logfile output io.Writer for every log
 // level to the given writer
 func SetWriter(level Level, w io.Writer, outputTgt int) {
+	resetTerminals()
 	for _, o := range outputters {
 		o.mu.Lock()
 		defer o.mu.Unlock()
@@ -703,7 +890,8 @@ func SetWriter(level Level, w io.Writer, outputTgt int) {
 // a newline then the below call can be used to tell the LvlOutput(s) that a
 // newline was hit and any fresh output can be prefixed cleanly:
 //   out.ResetNewline(true, out.ForScreen|out.ForLogfile)
-// Note: for any *output* running through this module this is auto-handled
+// Note: for any *output* running through this module this is auto-handled,
+// as it is for input read via out.Prompt() and friends (see prompt.go)
 func ResetNewline(val bool, outputTgt int) {
 	// Safely adjust these settings
 	mutex.Lock()
@@ -773,6 +961,53 @@ func UseTempLogFile(prefix string) string {
 	return safeLogFileName
 }
 
+// ReopenLogFile reopens the current log file (see LogFileName()) for all
+// levels writing to it, typically after an external tool (eg: logrotate) has
+// moved the file away so output goes to a fresh file of the same name, the
+// old file handle is closed.  If no log file is in use nothing is done.
+func ReopenLogFile() error {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if logFileName == "" {
+		return nil
+	}
+	for _, o := range outputters {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+	}
+	var oldFile, newFile *os.File
+	var rotateWr *RotateWriter
+	for _, o := range outputters {
+		switch wr := o.logfileHndl.(type) {
+		case *os.File:
+			if wr.Name() != logFileName {
+				continue
+			}
+			if newFile == nil {
+				var err error
+				newFile, err = os.OpenFile(logFileName, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0666)
+				if err != nil {
+					return err
+				}
+			}
+			oldFile = wr
+			o.logfileHndl = newFile
+		case *RotateWriter:
+			if wr.filename != logFileName || wr == rotateWr {
+				continue
+			}
+			if err := wr.reopen(); err != nil {
+				return err
+			}
+			rotateWr = wr
+		}
+	}
+	if oldFile != nil {
+		return oldFile.Close()
+	}
+	return nil
+}
+
 // Next we head into the <Level>() class methods which don't add newlines
 // and simply space separate the options sent to them:
 
@@ -784,7 +1019,7 @@ func Trace(v ...interface{}) {
 	terminate := false
 	exitVal := 0
 	mutex.Unlock()
-	TRACE.output(terminate, exitVal, v...)
+	TRACE.output(terminate, exitVal, outputOpts{}, v...)
 }
 
 // Debug is meant for basic debugging, space separate opts with no newline added
@@ -795,7 +1030,7 @@ func Debug(v ...interface{}) {
 	terminate := false
 	exitVal := 0
 	mutex.Unlock()
-	DEBUG.output(terminate, exitVal, v...)
+	DEBUG.output(terminate, exitVal, outputOpts{}, v...)
 }
 
 // Verbose meant for verbose user seen screen output, space separated
@@ -805,7 +1040,7 @@ func Verbose(v ...interface{}) {
 	terminate := false
 	exitVal := 0
 	mutex.Unlock()
-	VERBOSE.output(terminate, exitVal, v...)
+	VERBOSE.output(terminate, exitVal, outputOpts{}, v...)
 }
 
 // Print is meant for "normal" user output, space separated opted
@@ -815,7 +1050,7 @@ func Print(v ...interface{}) {
 	terminate := false
 	exitVal := 0
 	mutex.Unlock()
-	INFO.output(terminate, exitVal, v...)
+	INFO.output(terminate, exitVal, outputOpts{}, v...)
 }
 
 // Info is the same as Print: meant for "normal" user output, space separated
@@ -825,7 +1060,20 @@ func Info(v ...interface{}) {
 	terminate := false
 	exitVal := 0
 	mutex.Unlock()
-	INFO.output(terminate, exitVal, v...)
+	INFO.output(terminate, exitVal, outputOpts{}, v...)
+}
+
+// MustShow is Info output that is always shown on the screen, regardless of
+// the screen threshold and quiet mode (see SetQuiet()), but not in silent mode
+// (see SetSilent()), eg: the summary lines a tool must always print, space
+// separated opts printed with no newline added.  See Always() for must-show
+// output at other levels.
+func MustShow(v ...interface{}) {
+	mutex.Lock()
+	terminate := false
+	exitVal := 0
+	mutex.Unlock()
+	INFO.output(terminate, exitVal, outputOpts{mustShow: true}, v...)
 }
 
 // Note is meant for output of key "note" the user should pay attention to, opts
@@ -836,7 +1084,7 @@ func Note(v ...interface{}) {
 	terminate := false
 	exitVal := 0
 	mutex.Unlock()
-	NOTE.output(terminate, exitVal, v...)
+	NOTE.output(terminate, exitVal, outputOpts{}, v...)
 }
 
 // Issue is meant for "normal" user error output, space separated opts
@@ -847,7 +1095,7 @@ func Issue(v ...interface{}) {
 	terminate := false
 	exitVal := 0
 	mutex.Unlock()
-	ISSUE.output(terminate, exitVal, v...)
+	ISSUE.output(terminate, exitVal, outputOpts{}, v...)
 }
 
 // IssueExit is meant for "normal" user error output, space separated opts
@@ -858,7 +1106,7 @@ func IssueExit(exitVal int, v ...interface{}) {
 	mutex.Lock()
 	terminate := true
 	mutex.Unlock()
-	ISSUE.output(terminate, exitVal, v...)
+	ISSUE.output(terminate, exitVal, outputOpts{}, v...)
 }
 
 // Error is meant for "unexpected"/system error output, space separated
@@ -871,7 +1119,7 @@ func Error(v ...interface{}) {
 	terminate := false
 	exitVal := 0
 	mutex.Unlock()
-	ERROR.output(terminate, exitVal, v...)
+	ERROR.output(terminate, exitVal, outputOpts{}, v...)
 }
 
 // ErrorExit is meant for "unexpected"/system error output, space separated
@@ -884,7 +1132,7 @@ func ErrorExit(exitVal int, v ...interface{}) {
 	mutex.Lock()
 	terminate := true
 	mutex.Unlock()
-	ERROR.output(terminate, exitVal, v...)
+	ERROR.output(terminate, exitVal, outputOpts{}, v...)
 }
 
 // Fatal is meant for "unexpected"/system fatal error output, space separated
@@ -895,7 +1143,7 @@ func Fatal(v ...interface{}) {
 	terminate := true
 	exitVal := int(atomic.LoadInt32(&errorExitVal))
 	mutex.Unlock()
-	FATAL.output(terminate, exitVal, v...)
+	FATAL.output(terminate, exitVal, outputOpts{}, v...)
 }
 
 // Next we head into the <Level>ln() class methods which add newlines
@@ -909,7 +1157,7 @@ func Traceln(v ...interface{}) {
 	terminate := false
 	exitVal := 0
 	mutex.Unlock()
-	TRACE.outputln(terminate, exitVal, v...)
+	TRACE.outputln(terminate, exitVal, outputOpts{}, v...)
 }
 
 // Debugln is meant for basic debugging, space separate opts with newline added
@@ -920,7 +1168,7 @@ func Debugln(v ...interface{}) {
 	terminate := false
 	exitVal := 0
 	mutex.Unlock()
-	DEBUG.outputln(terminate, exitVal, v...)
+	DEBUG.outputln(terminate, exitVal, outputOpts{}, v...)
 }
 
 // Verboseln is meant for verbose user seen screen output, space separated
@@ -930,7 +1178,7 @@ func Verboseln(v ...interface{}) {
 	terminate := false
 	exitVal := 0
 	mutex.Unlock()
-	VERBOSE.outputln(terminate, exitVal, v...)
+	VERBOSE.outputln(terminate, exitVal, outputOpts{}, v...)
 }
 
 // Println is the same as Infoln: meant for "normal" user output, space
@@ -941,7 +1189,7 @@ func Println(v ...interface{}) {
 	terminate := false
 	exitVal := 0
 	mutex.Unlock()
-	INFO.outputln(terminate, exitVal, v...)
+	INFO.outputln(terminate, exitVal, outputOpts{}, v...)
 }
 
 // Infoln is the same as Println: meant for "normal" user output, space
@@ -952,7 +1200,18 @@ func Infoln(v ...interface{}) {
 	terminate := false
 	exitVal := 0
 	mutex.Unlock()
-	INFO.outputln(terminate, exitVal, v...)
+	INFO.outputln(terminate, exitVal, outputOpts{}, v...)
+}
+
+// MustShowln is Infoln output that is always shown on the screen, regardless
+// of the screen threshold and quiet mode (but not in silent mode), see
+// MustShow(), space separated opts printed with newline added
+func MustShowln(v ...interface{}) {
+	mutex.Lock()
+	terminate := false
+	exitVal := 0
+	mutex.Unlock()
+	INFO.outputln(terminate, exitVal, outputOpts{mustShow: true}, v...)
 }
 
 // Noteln is meant for output of key items the user should pay attention to,
@@ -963,7 +1222,7 @@ func Noteln(v ...interface{}) {
 	terminate := false
 	exitVal := 0
 	mutex.Unlock()
-	NOTE.outputln(terminate, exitVal, v...)
+	NOTE.outputln(terminate, exitVal, outputOpts{}, v...)
 }
 
 // Issueln is meant for "normal" user error output, space separated
@@ -976,7 +1235,7 @@ func Issueln(v ...interface{}) {
 	terminate := false
 	exitVal := 0
 	mutex.Unlock()
-	ISSUE.outputln(terminate, exitVal, v...)
+	ISSUE.outputln(terminate, exitVal, outputOpts{}, v...)
 }
 
 // IssueExitln is meant for "normal" user error output, space separated opts
@@ -989,7 +1248,7 @@ func IssueExitln(exitVal int, v ...interface{}) {
 	mutex.Lock()
 	terminate := true
 	mutex.Unlock()
-	ISSUE.outputln(terminate, exitVal, v...)
+	ISSUE.outputln(terminate, exitVal, outputOpts{}, v...)
 }
 
 // Errorln is meant for "unexpected"/system error output, space separated
@@ -1001,7 +1260,7 @@ func Errorln(v ...interface{}) {
 	terminate := false
 	exitVal := 0
 	mutex.Unlock()
-	ERROR.outputln(terminate, exitVal, v...)
+	ERROR.outputln(terminate, exitVal, outputOpts{}, v...)
 }
 
 // ErrorExitln is meant for "unexpected"/system error output, space separated
@@ -1016,7 +1275,7 @@ func ErrorExitln(exitVal int, v ...interface{}) {
 	mutex.Lock()
 	terminate := true
 	mutex.Unlock()
-	ERROR.outputln(terminate, exitVal, v...)
+	ERROR.outputln(terminate, exitVal, outputOpts{}, v...)
 }
 
 // Fatalln is meant for "unexpected"/system fatal error output, space separated
@@ -1029,7 +1288,7 @@ func Fatalln(v ...interface{}) {
 	terminate := true
 	exitVal := int(atomic.LoadInt32(&errorExitVal))
 	mutex.Unlock()
-	FATAL.outputln(terminate, exitVal, v...)
+	FATAL.outputln(terminate, exitVal, outputOpts{}, v...)
 }
 
 // Next we head into the <Level>f() class methods which take a standard
@@ -1043,7 +1302,7 @@ func Tracef(format string, v ...interface{}) {
 	terminate := false
 	exitVal := 0
 	mutex.Unlock()
-	TRACE.outputf(terminate, exitVal, format, v...)
+	TRACE.outputf(terminate, exitVal, outputOpts{}, format, v...)
 }
 
 // Debugf is meant for basic debugging, format string followed by args and
@@ -1054,7 +1313,7 @@ func Debugf(format string, v ...interface{}) {
 	terminate := false
 	exitVal := 0
 	mutex.Unlock()
-	DEBUG.outputf(terminate, exitVal, format, v...)
+	DEBUG.outputf(terminate, exitVal, outputOpts{}, format, v...)
 }
 
 // Verbosef is meant for verbose user seen screen output, format string
@@ -1064,7 +1323,7 @@ func Verbosef(format string, v ...interface{}) {
 	terminate := false
 	exitVal := 0
 	mutex.Unlock()
-	VERBOSE.outputf(terminate, exitVal, format, v...)
+	VERBOSE.outputf(terminate, exitVal, outputOpts{}, format, v...)
 }
 
 // Printf is the same as Infoln: meant for "normal" user output, format string
@@ -1074,7 +1333,7 @@ func Printf(format string, v ...interface{}) {
 	terminate := false
 	exitVal := 0
 	mutex.Unlock()
-	INFO.outputf(terminate, exitVal, format, v...)
+	INFO.outputf(terminate, exitVal, outputOpts{}, format, v...)
 }
 
 // Infof is the same as Printf: meant for "normal" user output, format string
@@ -1084,7 +1343,18 @@ func Infof(format string, v ...interface{}) {
 	terminate := false
 	exitVal := 0
 	mutex.Unlock()
-	INFO.outputf(terminate, exitVal, format, v...)
+	INFO.outputf(terminate, exitVal, outputOpts{}, format, v...)
+}
+
+// MustShowf is Infof output that is always shown on the screen, regardless
+// of the screen threshold and quiet mode (but not in silent mode), see
+// MustShow(), format string followed by args
+func MustShowf(format string, v ...interface{}) {
+	mutex.Lock()
+	terminate := false
+	exitVal := 0
+	mutex.Unlock()
+	INFO.outputf(terminate, exitVal, outputOpts{mustShow: true}, format, v...)
 }
 
 // Notef is meant for output of key "note" the user should pay attention to,
@@ -1094,7 +1364,7 @@ func Notef(format string, v ...interface{}) {
 	terminate := false
 	exitVal := 0
 	mutex.Unlock()
-	NOTE.outputf(terminate, exitVal, format, v...)
+	NOTE.outputf(terminate, exitVal, outputOpts{}, format, v...)
 }
 
 // Issuef is meant for "normal" user error output, format string followed
@@ -1105,7 +1375,7 @@ func Issuef(format string, v ...interface{}) {
 	terminate := false
 	exitVal := 0
 	mutex.Unlock()
-	ISSUE.outputf(terminate, exitVal, format, v...)
+	ISSUE.outputf(terminate, exitVal, outputOpts{}, format, v...)
 }
 
 // IssueExitf is meant for "normal" user error output, format string followed
@@ -1116,7 +1386,7 @@ func IssueExitf(exitVal int, format string, v ...interface{}) {
 	mutex.Lock()
 	terminate := true
 	mutex.Unlock()
-	ISSUE.outputf(terminate, exitVal, format, v...)
+	ISSUE.outputf(terminate, exitVal, outputOpts{}, format, v...)
 }
 
 // Errorf is meant for "unexpected"/system error output, format string
@@ -1128,7 +1398,7 @@ func Errorf(format string, v ...interface{}) {
 	terminate := false
 	exitVal := 0
 	mutex.Unlock()
-	ERROR.outputf(terminate, exitVal, format, v...)
+	ERROR.outputf(terminate, exitVal, outputOpts{}, format, v...)
 }
 
 // ErrorExitf is meant for "unexpected"/system error output, format string
@@ -1138,7 +1408,7 @@ func ErrorExitf(exitVal int, format string, v ...interface{}) {
 	mutex.Lock()
 	terminate := true
 	mutex.Unlock()
-	ERROR.outputf(terminate, exitVal, format, v...)
+	ERROR.outputf(terminate, exitVal, outputOpts{}, format, v...)
 }
 
 // Fatalf is meant for "unexpected"/system fatal error output, format string
@@ -1149,7 +1419,7 @@ func Fatalf(format string, v ...interface{}) {
 	terminate := true
 	exitVal := int(atomic.LoadInt32(&errorExitVal))
 	mutex.Unlock()
-	FATAL.outputf(terminate, exitVal, format, v...)
+	FATAL.outputf(terminate, exitVal, outputOpts{}, format, v...)
 }
 
 // Exit is meant for terminating without messaging but supporting stack trace
@@ -1162,6 +1432,54 @@ func Exit(exitVal int) {
 	}
 }
 
+// DebugScope returns the current debug scope, see SetDebugScope()
+func DebugScope() string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return debugScope
+}
+
+// SetDebugScope restricts trace and debug level output to the packages or
+// functions given (comma separated, a simple substring match is done against
+// the full function name, eg: "github.com/dvln/out.Method"), use "" to remove
+// the restriction.  If the PKG_OUT_DEBUG_SCOPE env var is set it overrides
+// this setting.
+func SetDebugScope(scope string) {
+	mutex.Lock()
+	{
+		debugScope = scope
+	}
+	mutex.Unlock()
+}
+
+// currentDebugScope returns the debug scope in effect, the PKG_OUT_DEBUG_SCOPE
+// env var if set otherwise the scope set via SetDebugScope()
+func currentDebugScope() string {
+	if scope := os.Getenv("PKG_OUT_DEBUG_SCOPE"); scope != "" {
+		return scope
+	}
+	return DebugScope()
+}
+
+// Count returns the number of messages sent to the given output level since
+// the program started, whether or not they were output (see Threshold())
+func Count(level Level) uint64 {
+	level = levelCheck(level)
+	if level == LevelDiscard {
+		return 0
+	}
+	return atomic.LoadUint64(&levelCounts[level])
+}
+
+// StackTraceConfig returns the current stack trace config settings, see the
+// SetStackTraceConfig() routine for details on the settings
+func StackTraceConfig() int {
+	mutex.Lock()
+	cfg := stackTraceConfig
+	mutex.Unlock()
+	return cfg
+}
+
 // SetStackTraceConfig can be used to control when stack traces are dumped
 // in errors (or issues/warnings).  The settings are controlled via these
 // flags (defined globally for this pkg):
@@ -1191,6 +1509,46 @@ func SetStackTraceConfig(cfg int) {
 	mutex.Unlock()
 }
 
+// parseStackTraceConfig maps the string form of the stack trace config as
+// used in the PKG_OUT_STACK_TRACE_CONFIG env var (eg: "both,allissues") to
+// the config settings (see SetStackTraceConfig()), "off" turns stack traces
+// off, an error is returned for an unknown or incomplete config
+func parseStackTraceConfig(str string) (int, error) {
+	cfg := 0
+	settings := strings.Split(str, ",")
+	if len(settings) == 1 && strings.ToLower(strings.TrimSpace(settings[0])) == "off" {
+		return 0, nil
+	}
+	if len(settings) != 2 {
+		return 0, fmt.Errorf("invalid stack trace config %q, expected \"<target>,<when>\" or \"off\" (eg: \"logfile,nonzeroerrorexit\")", str)
+	}
+	for _, currSetting := range settings {
+		currSetting = strings.ToLower(strings.TrimSpace(currSetting))
+		switch currSetting {
+		case "both":
+			cfg = cfg | ForBoth
+		case "screen":
+			cfg = cfg | ForScreen
+		case "logfile":
+			cfg = cfg | ForLogfile
+		case "nonzeroerrorexit":
+			cfg = cfg | StackTraceNonZeroErrorExit
+		case "errorexit":
+			cfg = cfg | StackTraceErrorExit
+		case "allissues", "all":
+			cfg = cfg | StackTraceAllIssues
+		case "off", "never":
+			return 0, nil
+		default:
+			return 0, fmt.Errorf("invalid stack trace config %q, unknown setting %q (valid: both, screen, logfile, nonzeroerrorexit, errorexit, allissues, never, off)", str, currSetting)
+		}
+	}
+	if cfg&ForBoth == 0 || cfg&(StackTraceNonZeroErrorExit|StackTraceErrorExit|StackTraceAllIssues) == 0 {
+		return 0, fmt.Errorf("invalid stack trace config %q, expected \"<target>,<when>\" or \"off\" (eg: \"logfile,nonzeroerrorexit\")", str)
+	}
+	return cfg, nil
+}
+
 // getStackTrace will get a stack trace (of the desired depth) and return
 // it.  Currently callDepth is used assuming this is being called from the
 // defined routes into the 'out' pkg (ie: this will map to where 'out' was
@@ -1229,31 +1587,20 @@ func getStackTrace(detErr DetailedError, depth ...int) string {
 //     AlwaysInsert            // Prefix every line, regardless of output history
 //     BlankInsert             // Only spaces inserted (same length as prefix)
 //     SkipFirstLine           // 1st line in multi-line string has no prefix
+//     BlankContinue           // Prefix the 1st line, blanks on the rest
 //     SmartInsert             // See doPrefixing(), only handled there now
 // - errCode: attempt to insert any valid error code into the prefix, eg:
 //     // a prefix of "Error: " would become "Error #<errcode>: "
 func InsertPrefix(s string, prefix string, ctrl int, errCode int) string {
-	// FEATURE: add ability to prefix the 1st line only (smartly or always) and
-	//          then "blank prefix" the rest of the lines (readability better?)
-
 	if prefix == "" {
 		return s
 	}
 	if ctrl&AlwaysInsert != 0 {
 		ctrl = 0 // turn off everything, always means *always*
 	}
-	// If there is an error code of interest then insert it into the message
-	// if possible... braindead, must be something like "Error: " or "Issue: "
-	// and so a split on ":" results in two strings, results: "Error #<code>: "
-	if errCode > 0 && errCode != int(defaultErrCode) {
-		parts := strings.Split(prefix, ":")
-		if len(parts) == 2 {
-			prefix = parts[0] + fmt.Sprintf(" #%d:", errCode) + parts[1]
-		}
-	}
-	pfxLength := len(prefix)
-	format := "%" + fmt.Sprintf("%d", pfxLength) + "s"
-	spacePrefix := fmt.Sprintf(format, "")
+	prefix = insertErrCode(prefix, errCode)
+	// the blank prefix is as wide as the prefix shows up (eg: if colored)
+	spacePrefix := strings.Repeat(" ", displayWidth(prefix))
 	lines := strings.Split(s, "\n")
 	numLines := len(lines)
 	newLines := []string{}
@@ -1263,8 +1610,9 @@ func InsertPrefix(s string, prefix string, ctrl int, errCode int) string {
 			// if last line and it's empty don't prefix it, add empty line or if
 			// it's the 1st line and we are to skip prefixing the 1st line:
 			newLines = append(newLines, line)
-		} else if ctrl&BlankInsert != 0 {
-			// if blank-only prefix desired then go with that for all lines
+		} else if ctrl&BlankInsert != 0 || (idx != 0 && ctrl&BlankContinue != 0) {
+			// if blank-only prefix desired then go with that for all lines (or
+			// all but the 1st line if only the 1st line is to be prefixed)
 			newLines = append(newLines, spacePrefix+line)
 		} else {
 			// otherwise prefix every line with given prefix
@@ -1275,6 +1623,19 @@ func InsertPrefix(s string, prefix string, ctrl int, errCode int) string {
 	return newstr
 }
 
+// insertErrCode inserts any error code of interest into the prefix if
+// possible... braindead, must be something like "Error: " or "Issue: " and
+// so a split on ":" results in two strings, results: "Error #<code>: "
+func insertErrCode(prefix string, errCode int) string {
+	if errCode > 0 && errCode != int(defaultErrCode) {
+		parts := strings.Split(prefix, ":")
+		if len(parts) == 2 {
+			prefix = parts[0] + fmt.Sprintf(" #%d:", errCode) + parts[1]
+		}
+	}
+	return prefix
+}
+
 // getAnyDetailedErrors will determine if, given a list of interfaces, any of
 // them are of interface type DetailedError and, if so, push them onto a
 // slice of DetailedError's
@@ -1290,101 +1651,134 @@ func getAnyDetailedErrors(v ...interface{}) []DetailedError {
 	return detErrs
 }
 
+// outputOpts holds the per-call output options threaded through to
+// stringOutput(), the zero value is normal output
+
+type outputOpts struct {
+	mustShow    bool             // show on the screen regardless of threshold and quiet mode
+	logfileOnly bool             // skip the screen, eg: prompt answers the user typed in
+	logfileMsg  string           // the logfile gets this message instead (if set)
+	records     [][]ErrField     // structured formats get these records (eg: table rows)
+	prefix      string           // a context prefix that follows the level prefix (see WithPrefix())
+	hinted      bool             // the message was rendered with a hinted error, see hintedMsgs()
+	screenMsg   string           // the screen gets this message instead (if hinted)
+	fieldsMsg   string           // structured formats get this message instead (if set), see fieldsMsg()
+	section     *SectionScope    // the section the output is in (if any), see withScopes()
+	group       *GroupScope      // the group the output is in (if any), see withScopes()
+	exitFunc    func(int)        // exit func used instead of the package one (if set)
+	clock       func() time.Time // clock used instead of the package one (if set)
+}
+
+// now returns the current time via the clock of the output options if set
+// (see Logger WithClock()), else via the package clock (see SetClock())
+func (opts outputOpts) now() time.Time {
+	if opts.clock != nil {
+		return opts.clock()
+	}
+	return now()
+}
+
+// withScopes returns the output options with the section and group that the
+// calling goroutine's output is in (see Section() and Group()), this is done
+// once per output call as the goroutine id takes a stack dump to find
+func (opts outputOpts) withScopes() outputOpts {
+	if atomic.LoadInt32(&sectionGoroutines) == 0 && atomic.LoadInt32(&groupGoroutines) == 0 {
+		return opts
+	}
+	gid := goroutineID()
+	opts.section = goroutineSection(gid)
+	opts.group = goroutineGroup(gid)
+	return opts
+}
+
 // output is similar to fmt.Print(), it'll space separate args with no newline
 // and output them to the screen and/or log file loggers based on levels
-func (o *LvlOutput) output(terminal bool, exitVal int, v ...interface{}) {
-	detErrs := getAnyDetailedErrors(v...)
-	var detErr DetailedError
-	if detErrs != nil {
-		detErr = detErrs[0]
-	}
+func (o *LvlOutput) output(terminal bool, exitVal int, opts outputOpts, v ...interface{}) {
+	detErr := getDetailedError(v...)
 	if detErr != nil {
 		// if we have a detailed error coming in at some output level insure
 		// that the output level used for that output matches the incoming
-		// output level always
+		// output level always (for each of the errors given, not just any
+		// MultiError they were aggregated into)
+		for _, argErr := range getAnyDetailedErrors(v...) {
+			argErr.SetLvlOut(o)
+		}
 		detErr.SetLvlOut(o)
 	}
 	// set up the message to dump
 	msg := fmt.Sprint(v...)
+	opts = hintedMsgs(opts, detErr, fmt.Sprint, v)
+	opts = fieldsMsg(opts, detErr, fmt.Sprint, v)
 
 	// dump msg based on screen and log output levels
-	_, err := o.stringOutput(msg, terminal, exitVal, detErr)
+	_, err := o.stringOutput(msg, terminal, exitVal, opts, detErr)
 	if err != nil {
 		mutex.Lock()
 		{
 			fmt.Fprintf(os.Stderr, "%s", err)
 		}
 		mutex.Unlock()
-		mutex.RLock()
-		if deferFunc != nil {
-			deferFunc(int(atomic.LoadInt32(&errorExitVal)))
-		}
-		mutex.RUnlock()
-		if os.Getenv("PKG_OUT_NO_EXIT") != "1" {
-			os.Exit(int(atomic.LoadInt32(&errorExitVal)))
-		}
+		terminate(int(atomic.LoadInt32(&errorExitVal)), opts.exitFunc)
+		return
+	}
+	// if we're dying off then we need to exit unless overrides in play
+	if terminal {
+		terminate(exitVal, opts.exitFunc)
 	}
 }
 
 // outputln is similar to fmt.Println(), it'll space separate args with no
 // newline and output them to the screen and/or log file loggers based on levels
-func (o *LvlOutput) outputln(terminal bool, exitVal int, v ...interface{}) {
+func (o *LvlOutput) outputln(terminal bool, exitVal int, opts outputOpts, v ...interface{}) {
 	// set up the message to dump
 	msg := fmt.Sprintln(v...)
 
-	detErrs := getAnyDetailedErrors(v...)
-	var detErr DetailedError
-	if detErrs != nil {
-		detErr = detErrs[0]
-	}
+	detErr := getDetailedError(v...)
+	opts = hintedMsgs(opts, detErr, fmt.Sprintln, v)
+	opts = fieldsMsg(opts, detErr, fmt.Sprintln, v)
 
 	// dump msg based on screen and log output levels
-	_, err := o.stringOutput(msg, terminal, exitVal, detErr)
+	_, err := o.stringOutput(msg, terminal, exitVal, opts, detErr)
 	if err != nil {
 		mutex.Lock()
 		{
 			fmt.Fprintf(os.Stderr, "%s", err)
 		}
 		mutex.Unlock()
-		mutex.RLock()
-		if deferFunc != nil {
-			deferFunc(int(atomic.LoadInt32(&errorExitVal)))
-		}
-		mutex.RUnlock()
-		if os.Getenv("PKG_OUT_NO_EXIT") != "1" {
-			os.Exit(int(atomic.LoadInt32(&errorExitVal)))
-		}
+		terminate(int(atomic.LoadInt32(&errorExitVal)), opts.exitFunc)
+		return
+	}
+	// if we're dying off then we need to exit unless overrides in play
+	if terminal {
+		terminate(exitVal, opts.exitFunc)
 	}
 }
 
 // outputf is similar to fmt.Printf(), it takes a format and args and outputs
 // the resulting string to the screen and/or log file loggers based on levels
-func (o *LvlOutput) outputf(terminal bool, exitVal int, format string, v ...interface{}) {
+func (o *LvlOutput) outputf(terminal bool, exitVal int, opts outputOpts, format string, v ...interface{}) {
 	// set up the message to dump
 	msg := fmt.Sprintf(format, v...)
 
-	detErrs := getAnyDetailedErrors(v...)
-	var detErr DetailedError
-	if detErrs != nil {
-		detErr = detErrs[0]
-	}
+	detErr := getDetailedError(v...)
+	render := func(a ...interface{}) string { return fmt.Sprintf(format, a...) }
+	opts = hintedMsgs(opts, detErr, render, v)
+	opts = fieldsMsg(opts, detErr, render, v)
 
 	// dump msg based on screen and log output levels
-	_, err := o.stringOutput(msg, terminal, exitVal, detErr)
+	_, err := o.stringOutput(msg, terminal, exitVal, opts, detErr)
 	if err != nil {
 		mutex.Lock()
 		{
 			fmt.Fprintf(os.Stderr, "%s", err)
 		}
 		mutex.Unlock()
-		mutex.RLock()
-		if deferFunc != nil {
-			deferFunc(int(atomic.LoadInt32(&errorExitVal)))
-		}
-		mutex.RUnlock()
-		if os.Getenv("PKG_OUT_NO_EXIT") != "1" {
-			os.Exit(int(atomic.LoadInt32(&errorExitVal)))
-		}
+		terminate(int(atomic.LoadInt32(&errorExitVal)), opts.exitFunc)
+		return
+	}
+	// if we're dying off then we need to exit unless overrides in play
+	if terminal {
+		terminate(exitVal, opts.exitFunc)
 	}
 }
 
@@ -1399,29 +1793,9 @@ func (o *LvlOutput) stackTraceWanted(terminal bool, exitVal int, outputTgt int)
 	defer mutex.Unlock()
 	val := os.Getenv("PKG_OUT_STACK_TRACE_CONFIG")
 	if val != "" {
-		newCfg := 0
-		settings := strings.Split(val, ",")
-		if len(settings) == 2 {
-			for _, currSetting := range settings {
-				currSetting = strings.ToLower(currSetting)
-				switch currSetting {
-				case "both":
-					newCfg = newCfg | ForBoth
-				case "screen":
-					newCfg = newCfg | ForScreen
-				case "logfile":
-					newCfg = newCfg | ForLogfile
-				case "nonzeroerrorexit":
-					newCfg = newCfg | StackTraceNonZeroErrorExit
-				case "errorexit":
-					newCfg = newCfg | StackTraceErrorExit
-				case "allissues", "all":
-					newCfg = newCfg | StackTraceAllIssues
-				case "off":
-					newCfg = 0
-				default:
-				}
-			}
+		// a bad "<target>,<when>" setting means no stack traces (as always)
+		newCfg, err := parseStackTraceConfig(val)
+		if err == nil || strings.Count(val, ",") == 1 {
 			stackCfg = newCfg
 		}
 	}
@@ -1472,43 +1846,58 @@ func (o *LvlOutput) exit(exitVal int) {
 	terminal := true
 	safeLogThreshold := logThreshold
 	safeScreenThreshold := screenThreshold
+	safeQuiet := quiet
+	safeSilent := silent
 	mutex.Unlock()
 	o.mu.RLock()
 	level := o.level
 	o.mu.RUnlock()
-	if stacktrace != "" && o.stackTraceWanted(terminal, exitVal, ForScreen) && level >= safeScreenThreshold && level != LevelDiscard {
-		msg, _, suppressOutput := o.doPrefixing(stacktrace, ForScreen, SmartInsert, nil, false)
+	opts := outputOpts{}.withScopes()
+	if stacktrace != "" && o.stackTraceWanted(terminal, exitVal, ForScreen) && screenWanted(level, safeScreenThreshold, safeQuiet, safeSilent, false) {
+		msg, _, suppressOutput := o.doPrefixing(stacktrace, ForScreen, SmartInsert, nil, false, opts)
 		if !suppressOutput && msg != "" {
 			mutex.Lock()
 			_, err := o.screenHndl.Write([]byte(msg))
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "%sError writing stacktrace to screen output handle:\n%+v\n", o.prefix, err)
 				mutex.Unlock()
-				mutex.RLock()
-				if deferFunc != nil {
-					deferFunc(int(atomic.LoadInt32(&errorExitVal)))
-				}
-				mutex.RUnlock()
-				if os.Getenv("PKG_OUT_NO_EXIT") != "1" {
-					os.Exit(int(atomic.LoadInt32(&errorExitVal)))
-				}
+				terminate(int(atomic.LoadInt32(&errorExitVal)), nil)
 				mutex.Lock()
 			}
 			mutex.Unlock()
 		}
 	}
 	if stacktrace != "" && o.stackTraceWanted(terminal, exitVal, ForLogfile) && level >= safeLogThreshold && level != LevelDiscard {
-		msg, _, suppressOutput := o.doPrefixing(stacktrace, ForLogfile, SmartInsert, nil, false)
+		msg, _, suppressOutput := o.doPrefixing(stacktrace, ForLogfile, SmartInsert, nil, false, opts)
 		if !suppressOutput && msg != "" {
 			o.logfileHndl.Write([]byte(msg))
 		}
 	}
+	terminate(exitVal, nil)
+}
+
+// terminate runs any deferred function that has been set up (see SetDeferFunc)
+// and then exits with the given exit value via the given exit func or, if nil,
+// the package exit func (os.Exit() by default, see SetExitFunc()).  If the env
+// var PKG_OUT_NO_EXIT is set to "1" the default os.Exit() is skipped, this is
+// deprecated (use SetExitFunc()).
+func terminate(exitVal int, eFunc func(exitVal int)) {
+	// don't leave any grouped screen output behind (see Group())
+	flushGroups()
+	// grab the defer and exit funcs and release the lock before calling them
+	// so the defer func can safely use the 'out' package to dump final output
 	mutex.RLock()
-	if deferFunc != nil {
-		deferFunc(exitVal)
+	dFunc := deferFunc
+	if eFunc == nil {
+		eFunc = exitFunc
 	}
 	mutex.RUnlock()
-	if os.Getenv("PKG_OUT_NO_EXIT") != "1" {
+	if dFunc != nil {
+		dFunc(exitVal)
+	}
+	if eFunc != nil {
+		eFunc(exitVal)
+	} else if os.Getenv("PKG_OUT_NO_EXIT") != "1" {
 		os.Exit(exitVal)
 	}
 }
@@ -1621,6 +2010,33 @@ func getFlagString(buf *[]byte, flags int, level Level, funcName string, file st
 	return fmt.Sprintf("%s", *buf)
 }
 
+// flagNameList is the list of flag names understood by determineFlags()
+var flagNameList = []string{"debug", "all", "longall", "pid", "level", "date", "time", "micro", "microseconds", "file", "shortfile", "longfile", "func", "shortfunc", "longfunc", "off"}
+
+// parseFlags is the strict version of determineFlags(), it returns an error
+// if any of the comma separated flag names are unknown (spaces are ignored)
+func parseFlags(flagStr string) (int, error) {
+	var names []string
+	for _, name := range strings.Split(flagStr, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		known := false
+		for _, validName := range flagNameList {
+			if name == validName {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return 0, fmt.Errorf("unknown flag %q (valid: %s)", name, strings.Join(flagNameList, ", "))
+		}
+		names = append(names, name)
+	}
+	return determineFlags(strings.Join(names, ",")), nil
+}
+
 // determineFlags takes a set of flags defined in an env var (string) that
 // can be comma separated and turns them into a real flags store type (int) with
 // the desired settings, allows easy dynamic tweaking or addition of flags in
@@ -1679,10 +2095,11 @@ func determineFlags(flagStr string) int {
 //		SmartInsert       // See doPrefixing(), only handled there now
 //	overrideFlags (*int): get flags not from 'o' but here, else set to nil
 //	ignoreEnv (bool): ignore any env overrides/filters (eg: formatter wants all)
+//	opts (outputOpts): the output options, ie: any clock to use for the time
 // Returns the update msg string, any flag metadata available and if the output
 // should be suppressed (such as if debug scope doesn't include this module)
-func (o *LvlOutput) insertFlagMetadata(s string, outputTgt int, ctrl int, overrideFlags *int, ignoreEnv bool, depth ...int) (string, *FlagMetadata, bool) {
-	now := time.Now() // do this before Caller below, can take some time
+func (o *LvlOutput) insertFlagMetadata(s string, outputTgt int, ctrl int, overrideFlags *int, ignoreEnv bool, opts outputOpts, depth ...int) (string, *FlagMetadata, bool) {
+	stamp := opts.now() // do this before Caller below, can take some time
 	var file, funcName string
 	var line, flags int
 	var suppressOutput bool
@@ -1698,13 +2115,14 @@ func (o *LvlOutput) insertFlagMetadata(s string, outputTgt int, ctrl int, overri
 	lvlOutLevel := o.level
 	sF := o.screenFlags
 	lF := o.logFlags
+	metaColor := o.metaColor
 	if overrideFlags != nil {
 		sF = *overrideFlags
 		lF = *overrideFlags
 	}
 	o.mu.RUnlock()
 	flagMetadata.Level = fmt.Sprintf("%s", lvlOutLevel)
-	flagMetadata.Time = &now
+	flagMetadata.Time = &stamp
 	// if printing to the screen target use those flags, else use logfile flags
 	if outputTgt&ForScreen != 0 {
 		if str := os.Getenv("PKG_OUT_SCREEN_FLAGS"); !ignoreEnv && str != "" {
@@ -1724,8 +2142,9 @@ func (o *LvlOutput) insertFlagMetadata(s string, outputTgt int, ctrl int, overri
 		Fatalln("Invalid target passed to insertFlagMetadata():", outputTgt)
 	}
 	suppressOutput = false
+	scope := currentDebugScope()
 	if flags&(Lshortfile|Llongfile|Lshortfunc|Llongfunc) != 0 ||
-		(!ignoreEnv && os.Getenv("PKG_OUT_DEBUG_SCOPE") != "") {
+		(!ignoreEnv && scope != "") {
 		var ok bool
 		var pc uintptr
 		pc, file, line, ok = runtime.Caller(callerDepth)
@@ -1747,15 +2166,8 @@ func (o *LvlOutput) insertFlagMetadata(s string, outputTgt int, ctrl int, overri
 			// then suppress all debug output outside of the desired scope and
 			// only show those packages or methods of interest... simple substr
 			// match is done currently
-			if debugScope := os.Getenv("PKG_OUT_DEBUG_SCOPE"); funcName != "???" && debugScope != "" && (lvlOutLevel == LevelDebug || lvlOutLevel == LevelTrace) {
-				scopeParts := strings.Split(debugScope, ",")
-				suppressOutput = true
-				for _, scopePart := range scopeParts {
-					if strings.Contains(funcName, scopePart) {
-						suppressOutput = false
-						break
-					}
-				}
+			if funcName != "???" && (lvlOutLevel == LevelDebug || lvlOutLevel == LevelTrace) {
+				suppressOutput = !inDebugScope(funcName)
 			}
 		}
 		flagMetadata.Func = funcName
@@ -1765,12 +2177,15 @@ func (o *LvlOutput) insertFlagMetadata(s string, outputTgt int, ctrl int, overri
 	}
 	o.mu.Lock()
 	o.buf = o.buf[:0]
-	leader := getFlagString(&o.buf, flags, level, funcName, file, line, now)
+	leader := getFlagString(&o.buf, flags, level, funcName, file, line, stamp)
 	flagMetadata.PID = os.Getpid()
 	o.mu.Unlock()
 	if leader == "" {
 		return s, flagMetadata, suppressOutput
 	}
+	if outputTgt&ForScreen != 0 && metaColor != "" && o.screenColor() {
+		leader = colorize(leader, metaColor)
+	}
 	// Use 0 as the error code as we don't want to try and insert any error
 	// code in standard flags prefix (that's only needed for errs/warnings),
 	// so just do a full prefixing of the flags data
@@ -1797,6 +2212,8 @@ func (o *LvlOutput) insertFlagMetadata(s string, outputTgt int, ctrl int, overri
 // calculation to see if we should dump this line based on trace/debug scope
 // info (which can only be calculated once we figure out what pkg/func is
 // being dumped... which, you guessed it, happens right here now).
+// - opts: the output options, ie: any context prefix to put after the level
+// prefix (see WithPrefix()) and the section and group the output is in
 // Routine returns:
 // - s (string): the prefixed string (no pfx added if checkSuppressOnly is true)
 // - suppressOutput (bool): indicates if output should be suppressed due to
@@ -1845,15 +2262,22 @@ func (o *LvlOutput) insertFlagMetadata(s string, outputTgt int, ctrl int, overri
 //   <date/time> myfile.go:37: Fatal: Severe error, giving up
 //   <date/time> myfile.go:37: Fatal:
 //   <date/time> myfile.go:37: Fatal: Stack Trace: <multiline stacktrace here>
-func (o *LvlOutput) doPrefixing(s string, outputTgt int, ctrl int, detErr DetailedError, checkSuppressOnly bool) (string, *FlagMetadata, bool) {
+func (o *LvlOutput) doPrefixing(s string, outputTgt int, ctrl int, detErr DetailedError, checkSuppressOnly bool, opts outputOpts) (string, *FlagMetadata, bool) {
 	// Where we check out if we previously had no newline and if so the
 	// first line (if multiline) will not have the prefix, see example
 	// in function header around username
 	origString := s
 	var onNewline bool
+	var groupPrefix string
+	group := opts.group
 	mutex.Lock()
 	scrNewline := screenNewline
 	logNewline := logfileNewline
+	if group != nil {
+		// grouped screen output tracks its own newlines (see Group())
+		scrNewline = group.newline
+		groupPrefix = group.groupPrefixLocked(outputTgt)
+	}
 	mutex.Unlock()
 	if outputTgt&ForScreen != 0 {
 		onNewline = scrNewline
@@ -1871,9 +2295,23 @@ func (o *LvlOutput) doPrefixing(s string, outputTgt int, ctrl int, detErr Detail
 	}
 	o.mu.RLock()
 	prefix := o.prefix
+	prefixColor := o.prefixColor
 	o.mu.RUnlock()
+	// Color the prefix for the screen if wanted (never for the logfile)
+	if outputTgt&ForScreen != 0 && prefixColor != "" && o.screenColor() {
+		prefix = colorize(prefix, prefixColor)
+	}
+	// Any error code goes into the level prefix, then any context prefix
+	// (see WithPrefix()) follows it
+	prefix = insertErrCode(prefix, errCode) + opts.prefix
+	// Output within a section is indented on the screen and has the section
+	// path in front of it in the logfile (see Section())
+	if opts.section != nil {
+		prefix = opts.section.sectionPrefix(outputTgt) + prefix
+	}
+	prefix = groupPrefix + prefix
 	// Insert prefix for this logging level
-	s = InsertPrefix(s, prefix, ctrl, errCode)
+	s = InsertPrefix(s, prefix, ctrl, 0)
 
 	if os.Getenv("PKG_OUT_SMART_FLAGS_PREFIX") == "off" {
 		ctrl = AlwaysInsert // forcibly add prefix without smarts
@@ -1882,9 +2320,14 @@ func (o *LvlOutput) doPrefixing(s string, outputTgt int, ctrl int, detErr Detail
 	// it has the brains to not add in a prefix if not needed or wanted
 	var suppressOutput bool
 	var flagMetadata *FlagMetadata
-	s, flagMetadata, suppressOutput = o.insertFlagMetadata(s, outputTgt, ctrl, nil, false)
+	s, flagMetadata, suppressOutput = o.insertFlagMetadata(s, outputTgt, ctrl, nil, false, opts)
 	if checkSuppressOnly {
 		s = origString // use non-pfx string *but* return suppressOutput result
+	} else if outputTgt&ForScreen != 0 {
+		// Soft wrap long lines to the screen width if wanted (see SetWrap())
+		if width := o.screenWrapWidth(); width > 0 {
+			s = wrapPrefixed(s, origString, width)
+		}
 	}
 	return s, flagMetadata, suppressOutput
 }
@@ -1899,10 +2342,11 @@ func (o *LvlOutput) doPrefixing(s string, outputTgt int, ctrl int, detErr Detail
 // - exitVal (int): what exit value is (only used if dying is true)
 // - stacktrace (string): if given and stack requested it will be added, note
 // that it is already pre-formatted
+// - group (*GroupScope): the group the output is in (if any), see Group()
 // Returns:
 // - int: number of bytes written to the io.Writer associated with outputTgt
 // - error: if any unexpected write error occurred this will be a raw Go error
-func (o *LvlOutput) writeOutput(s string, outputTgt int, dying bool, exitVal int, stacktrace string) (int, error) {
+func (o *LvlOutput) writeOutput(s string, outputTgt int, dying bool, exitVal int, stacktrace string, group *GroupScope) (int, error) {
 	tgtString := "logfile"
 	o.mu.RLock()
 	prefix := o.prefix
@@ -1921,10 +2365,27 @@ func (o *LvlOutput) writeOutput(s string, outputTgt int, dying bool, exitVal int
 		mutex.Unlock()
 	}
 	writeLength := 0
+	// Screen writes keep any status lines below the output (see NewStatus())
+	write := hndl.Write
+	if outputTgt&ForScreen != 0 {
+		write = func(b []byte) (int, error) { return screenWriteLocked(hndl, b) }
+		// Screen output within a group is buffered (see Group()), unless
+		// dying in which case the group's output is flushed first
+		if group != nil {
+			mutex.Lock()
+			if dying {
+				group.flushLocked()
+			} else {
+				write = func(b []byte) (int, error) { return group.bufferLocked(hndl, b) }
+				tgtStreamNewline = &group.newline
+			}
+			mutex.Unlock()
+		}
+	}
 
 	// Safely do writes and adjust settings as needed
 	mutex.Lock()
-	n, err := hndl.Write([]byte(s))
+	n, err := write([]byte(s))
 	mutex.Unlock()
 	writeLength += n
 	if err != nil {
@@ -1941,7 +2402,7 @@ func (o *LvlOutput) writeOutput(s string, outputTgt int, dying bool, exitVal int
 	}
 	if dying && !*tgtStreamNewline {
 		// ignore errors, just quick "prettyup" attempt:
-		n, err = hndl.Write([]byte("\n"))
+		n, err = write([]byte("\n"))
 		writeLength += n
 		if err != nil {
 			writeErr := fmt.Errorf("%sError writing newline to %s output handler:\n%+v\n", prefix, tgtString, err)
@@ -1956,7 +2417,7 @@ func (o *LvlOutput) writeOutput(s string, outputTgt int, dying bool, exitVal int
 	// See if stack trace is needed...
 	if o.stackTraceWanted(dying, exitVal, outputTgt) {
 		mutex.Lock()
-		n, err = hndl.Write([]byte(stacktrace))
+		n, err = write([]byte(stacktrace))
 		mutex.Unlock()
 		writeLength += n
 		if err != nil {
@@ -1979,12 +2440,15 @@ func (o *LvlOutput) writeOutput(s string, outputTgt int, dying bool, exitVal int
 // WARNING: this will silently ignore multiple detailed errors if you give it
 // more than one and simply use the 1st one given (that syntax is just used
 // to make the parameter optional to the stringOutput() method)
-func (o *LvlOutput) stringOutput(s string, dying bool, exitVal int, detErrs ...DetailedError) (int, error) {
+// The output options (opts) hold any per-call settings, eg: must-show output.
+// Note: this will not exit if dying, the caller must do that via terminate()
+func (o *LvlOutput) stringOutput(s string, dying bool, exitVal int, opts outputOpts, detErrs ...DetailedError) (int, error) {
 	// print to the screen output writer first...
 	var detErr DetailedError
 	if detErrs != nil {
 		detErr = detErrs[0]
 	}
+	opts = opts.withScopes()
 	var err error
 	var screenLength int
 	var logfileLength int
@@ -1994,6 +2458,7 @@ func (o *LvlOutput) stringOutput(s string, dying bool, exitVal int, detErrs ...D
 	level := o.level
 	formatter := o.formatter
 	o.mu.RUnlock()
+	atomic.AddUint64(&levelCounts[level], 1)
 
 	mutex.Lock()
 	forScreen := ForScreen
@@ -2001,6 +2466,10 @@ func (o *LvlOutput) stringOutput(s string, dying bool, exitVal int, detErrs ...D
 	smartInsert := SmartInsert
 	safeScreenThreshold := screenThreshold
 	safeLogThreshold := logThreshold
+	safeQuiet := quiet
+	safeSilent := silent
+	safeScreenFormat := screenFormat
+	safeLogfileFormat := logfileFormat
 	mutex.Unlock()
 
 	// Grab the best stack trace we can find to use in case it's needed, but
@@ -2023,10 +2492,31 @@ func (o *LvlOutput) stringOutput(s string, dying bool, exitVal int, detErrs ...D
 	// start independently tracking the screen and logfile output details
 	screenStr := s
 	logfileStr := s
+	if opts.logfileMsg != "" {
+		logfileStr = opts.logfileMsg
+	}
+	var hint, remediation, screenHints string
+	if detErr != nil {
+		// If the error has user facing hints the screen gets the short form
+		// of the error followed by the hints, the logfile gets everything
+		hint, remediation = Hint(detErr), Remediation(detErr)
+		if hint != "" || remediation != "" {
+			if opts.hinted {
+				screenStr = opts.screenMsg
+				logfileStackTrace = "" // already in logfile output, see above
+			}
+			screenHints = hintLines(hint, remediation, strings.HasSuffix(s, "\n"))
+		}
+	}
 	screenNoOutputMask := 0
 	logfileNoOutputMask := 0
 	screenSkipNativePfx := false
 	logfileSkipNativePfx := false
+	screenFormatted := false
+	logfileFormatted := false
+	// the flag metadata for formatters and structured formats is gathered
+	// here rather than via doPrefixing(), ie: one call level less deep
+	metaDepth := int(CallDepth()) - 1
 	if formatter != nil {
 		// If the client has registered a formatting interface method then
 		// lets give it a spin, may adjust the output or suppress it alltogether
@@ -2047,37 +2537,96 @@ func (o *LvlOutput) stringOutput(s string, dying bool, exitVal int, detErrs ...D
 		// Cheat a little and grab detailed output flags metadata for formatter,
 		// note that it will include the pid, level and date info automatically
 		flags := Llongfile | Llongfunc
-		_, flagMetadata, _ := o.insertFlagMetadata(s, forScreen, AlwaysInsert, &flags, true, 4)
+		_, flagMetadata, _ := o.insertFlagMetadata(s, forScreen, AlwaysInsert, &flags, true, opts, metaDepth)
 		if stackStr != "" {
 			flagMetadata.Stack = stackStr
 		}
+		if detErr != nil {
+			flagMetadata.Fields = fieldsMap(Fields(detErr))
+			flagMetadata.Hint = hint
+			flagMetadata.Remediation = remediation
+		}
 		resultStr, applyMask, noOutputMask, skipNativePfx = formatter.FormatMessage(s, level, code, dying, *flagMetadata)
 		// Based on formatter results set up screen and logfile output & controls
 		if applyMask&forScreen != 0 {
+			screenFormatted = true
 			screenNoOutputMask = noOutputMask
 			screenSkipNativePfx = skipNativePfx
 			screenStr = resultStr
+			screenHints = "" // formatter has taken over, it has the hints
 		}
 		if applyMask&forLogfile != 0 {
+			logfileFormatted = true
 			logfileNoOutputMask = noOutputMask
 			logfileSkipNativePfx = skipNativePfx
 			logfileStr = resultStr
 		}
 	}
 
+	// If a target has a structured output format (see SetOutputFormat()) then
+	// the message and its metadata are encoded in that format instead of the
+	// native prefixing (stack traces and hints included), unless a formatter
+	// has already taken over the output for that target
+	screenStructured := safeScreenFormat != "" && safeScreenFormat != FormatText && !screenFormatted
+	logfileStructured := safeLogfileFormat != "" && safeLogfileFormat != FormatText && !logfileFormatted
+	if screenStructured || logfileStructured {
+		code := 0
+		var fields []ErrField
+		if detErr != nil {
+			code = Code(detErr)
+			fields = Fields(detErr)
+		}
+		if opts.section != nil {
+			fields = append(fields, ErrField{"section", opts.section.Path()})
+		}
+		if opts.group != nil {
+			fields = append(fields, ErrField{"group", opts.group.Name()})
+		}
+		if opts.prefix != "" {
+			fields = append(fields, ErrField{"prefix", strings.TrimSpace(opts.prefix)})
+		}
+		flags := Llongfile | Llongfunc
+		_, flagMetadata, _ := o.insertFlagMetadata(s, forScreen, AlwaysInsert, &flags, true, opts, metaDepth)
+		flagMetadata.Hint = hint
+		flagMetadata.Remediation = remediation
+		msg := s
+		if opts.fieldsMsg != "" {
+			msg = opts.fieldsMsg
+		}
+		if screenStructured {
+			screenStr = structuredRecords(safeScreenFormat, msg, code, screenStackTrace, fields, flagMetadata, opts.records)
+			screenStackTrace = ""
+			screenHints = ""
+			screenSkipNativePfx = true
+		}
+		if logfileStructured {
+			logfileStr = structuredRecords(safeLogfileFormat, msg, code, logfileStackTrace, fields, flagMetadata, opts.records)
+			logfileStackTrace = ""
+			logfileSkipNativePfx = true
+		}
+	}
+
 	// Lets see if screen (here) or logfile (below) output is active:
-	if level >= safeScreenThreshold && level != LevelDiscard && screenNoOutputMask&forScreen == 0 {
+	if !opts.logfileOnly && screenWanted(level, safeScreenThreshold, safeQuiet, safeSilent, opts.mustShow) && screenNoOutputMask&forScreen == 0 {
 		// Screen output active based on output levels (and formatters, if any)
-		pfxScreenStr, _, suppressOutput := o.doPrefixing(screenStr, forScreen, smartInsert, detErr, screenSkipNativePfx)
+		pfxScreenStr, _, suppressOutput := o.doPrefixing(screenStr, forScreen, smartInsert, detErr, screenSkipNativePfx, opts)
 
 		// Note that suppressOutput is for suppressing trace/debug output so
 		// only selected/desired packages have debug output dumped (currently)
 		if !suppressOutput {
+			if screenHints != "" {
+				// hints are aligned under the error message (blank prefixed)
+				pfxHints, _, _ := o.doPrefixing(screenHints, forScreen, BlankInsert, detErr, screenSkipNativePfx, opts)
+				if !strings.HasSuffix(pfxScreenStr, "\n") {
+					pfxScreenStr += "\n"
+				}
+				pfxScreenStr += pfxHints
+			}
 			pfxStackTrace := ""
 			if screenStackTrace != "" {
-				pfxStackTrace, _, _ = o.doPrefixing(screenStackTrace, forScreen, smartInsert, detErr, screenSkipNativePfx)
+				pfxStackTrace, _, _ = o.doPrefixing(screenStackTrace, forScreen, smartInsert, detErr, screenSkipNativePfx, opts)
 			}
-			screenLength, err = o.writeOutput(pfxScreenStr, forScreen, dying, exitVal, pfxStackTrace)
+			screenLength, err = o.writeOutput(pfxScreenStr, forScreen, dying, exitVal, pfxStackTrace, opts.group)
 			if err != nil {
 				return screenLength, err
 			}
@@ -2086,37 +2635,114 @@ func (o *LvlOutput) stringOutput(s string, dying bool, exitVal int, detErrs ...D
 
 	// Print to the log file writer next (if needed):
 	if level >= safeLogThreshold && level != LevelDiscard && logfileNoOutputMask&forLogfile == 0 {
-		pfxLogfileStr, _, suppressOutput := o.doPrefixing(logfileStr, forLogfile, smartInsert, detErr, logfileSkipNativePfx)
+		pfxLogfileStr, _, suppressOutput := o.doPrefixing(logfileStr, forLogfile, smartInsert, detErr, logfileSkipNativePfx, opts)
 
 		// Note that suppressOutput is for suppressing trace/debug output so
 		// only selected/desired packages have debug output dumped (currently)
 		if !suppressOutput {
 			pfxStackTrace := ""
 			if logfileStackTrace != "" {
-				pfxStackTrace, _, _ = o.doPrefixing(logfileStackTrace, forLogfile, smartInsert, detErr, logfileSkipNativePfx)
+				pfxStackTrace, _, _ = o.doPrefixing(logfileStackTrace, forLogfile, smartInsert, detErr, logfileSkipNativePfx, opts)
 			}
-			logfileLength, err = o.writeOutput(pfxLogfileStr, forLogfile, dying, exitVal, pfxStackTrace)
+			logfileLength, err = o.writeOutput(pfxLogfileStr, forLogfile, dying, exitVal, pfxStackTrace, opts.group)
 			if err != nil {
 				return logfileLength + screenLength, err
 			}
 		}
 	}
-	// if we're dying off then we need to exit unless overrides in play,
-	// this env var should be used for test suites only really...
-	if dying {
-		mutex.RLock()
-		if deferFunc != nil {
-			deferFunc(int(atomic.LoadInt32(&errorExitVal)))
-		}
-		mutex.RUnlock()
-		if os.Getenv("PKG_OUT_NO_EXIT") != "1" {
-			os.Exit(int(atomic.LoadInt32(&errorExitVal)))
-		}
-	}
 	// if all good return all the bytes we wrote to *both* targets and nil err
 	return logfileLength + screenLength, nil
 }
 
+// renderedErr stands in for a hinted error among the args to output, it
+// formats (and spaces, for Print style output) the same as the error would
+type renderedErr struct {
+	msg string
+}
+
+// Error returns the rendered error message
+func (r renderedErr) Error() string {
+	return r.msg
+}
+
+// hintedMsgs renders the message again with the hinted detailed error among
+// the args in its screen form (just the most recent error message) and its
+// logfile form (the full error chain and stack trace) and sets these up in
+// the returned output options, only done if the error is one of the args
+func hintedMsgs(opts outputOpts, detErr DetailedError, render func(...interface{}) string, v []interface{}) outputOpts {
+	if detErr == nil || (Hint(detErr) == "" && Remediation(detErr) == "") {
+		return opts
+	}
+	idx := detErrArg(v)
+	if idx == -1 {
+		return opts
+	}
+	withStackTrace := true
+	shallow := true
+	prefix := false
+	args := append([]interface{}(nil), v...)
+	args[idx] = renderedErr{DefaultError(detErr, !withStackTrace, shallow, prefix)}
+	opts.screenMsg = render(args...)
+	args[idx] = renderedErr{DefaultError(detErr, withStackTrace, !shallow, prefix)}
+	opts.logfileMsg = render(args...)
+	opts.hinted = true
+	return opts
+}
+
+// fieldsMsg sets up the message for structured formats (see SetOutputFormat())
+// if the detailed error output has key/value fields, ie: the message with the
+// error rendered without the "Fields: .." line as structured records have the
+// fields as fields of their own
+func fieldsMsg(opts outputOpts, detErr DetailedError, render func(...interface{}) string, v []interface{}) outputOpts {
+	if detErr == nil || len(Fields(detErr)) == 0 {
+		return opts
+	}
+	idx := detErrArg(v)
+	if idx == -1 {
+		return opts
+	}
+	withStackTrace := true
+	shallow := true
+	prefix := false
+	withFields := true
+	args := append([]interface{}(nil), v...)
+	args[idx] = renderedErr{defaultError(detErr, !withStackTrace, !shallow, prefix, !withFields)}
+	opts.fieldsMsg = render(args...)
+	return opts
+}
+
+// detErrArg returns the index of the detailed error among the args to output,
+// -1 if there are none or several (those are combined into a MultiError)
+func detErrArg(v []interface{}) int {
+	idx := -1
+	for i, item := range v {
+		if _, ok := item.(DetailedError); ok {
+			if idx != -1 {
+				return -1
+			}
+			idx = i
+		}
+	}
+	return idx
+}
+
+// hintLines returns the user facing hint and remediation lines to show
+// under an error, newline terminated if requested
+func hintLines(hint, remediation string, newline bool) string {
+	var lines []string
+	if hint != "" {
+		lines = append(lines, "Hint: "+hint)
+	}
+	if remediation != "" {
+		lines = append(lines, "Remediation: "+remediation)
+	}
+	str := strings.Join(lines, "\n")
+	if newline {
+		str += "\n"
+	}
+	return str
+}
+
 // LevelWriter will return an io.Writer compatible structure for the desired
 // output level.  It's a bit cheesy but does the trick if you want an
 // io.Writer at a given level.  Typically one would not use this and
@@ -2171,7 +2797,7 @@ func (o *LvlOutput) Write(p []byte) (n int, err error) {
 	terminate := false
 	exitVal := 0
 	mutex.Unlock()
-	return o.stringOutput(string(p), terminate, exitVal)
+	return o.stringOutput(string(p), terminate, exitVal, outputOpts{})
 }
 
 // stackTrace returns a copy of the error with the stack trace field populated
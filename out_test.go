@@ -24,6 +24,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/dvln/testify/assert"
 )
@@ -37,24 +38,11 @@ import (
 //		t.Errorf("Trace should not write '%s'.", buf.String())
 //	}
 
-// ResetOutPkg is a rough 1st stab at resetting the 'out' pkg for testing
-// purposes so we can adjust settings, try things out then just "reset" them
-// before the next test.  See the TESTING comment below on current shortcomings.
+// ResetOutPkg resets the 'out' pkg for testing purposes so we can adjust
+// settings, try things out then just "reset" them before the next test, it
+// restores all settings to the starting defaults (see ResetDefaults()).
 func ResetOutPkg() {
-	// TESTING: currently prefixes, screenFlags, logfileFlags are not reset
-	//   via this reset routine (just do them yourself if you use them now),
-	//   but eventually that should be fixed to match the defaults (or perhaps
-	//   a deep copy of the TRACE, DEBUG, etc levels in init() and restore here)
-	ResetNewline(true, ForBoth)
-	SetThreshold(defaultScreenThreshold, ForScreen)
-	SetThreshold(defaultLogThreshold, ForLogfile)
-	SetStackTraceConfig(StackTraceExitToLogfile)
-	ClearFormatter(LevelAll)
-	// Clear the screen/log writers so they are set to the starting defaults
-	SetWriter(LevelAll, os.Stdout, ForScreen)
-	SetWriter(LevelFatal, os.Stderr, ForScreen)
-	SetWriter(LevelError, os.Stderr, ForScreen)
-	SetWriter(LevelAll, ioutil.Discard, ForLogfile)
+	ResetDefaults()
 }
 
 func TestLevels(t *testing.T) {
@@ -544,3 +532,79 @@ func TestLevelConversion(t *testing.T) {
 		t.Errorf("Failed to map error level to string and back")
 	}
 }
+
+func TestExitAndClockFuncs(t *testing.T) {
+	screenBuf := new(bytes.Buffer)
+	SetWriter(LevelAll, screenBuf, ForScreen)
+	SetFlags(LevelIssue, Ldate|Ltime, ForScreen)
+	exitVal := 0
+	exited := false
+	SetExitFunc(func(val int) {
+		exitVal = val
+		exited = true
+	})
+	SetClock(func() time.Time {
+		return time.Date(2016, time.March, 4, 5, 6, 7, 0, time.Local)
+	})
+
+	IssueExit(3, "time to go\n")
+
+	SetExitFunc(nil)
+	SetClock(nil)
+	SetFlags(LevelIssue, 0, ForScreen)
+	ResetOutPkg()
+
+	if !exited {
+		t.Errorf("Exit func was not called for IssueExit()")
+	}
+	assert.Equal(t, 3, exitVal)
+	assert.Equal(t, "2016/03/04 05:06:07 Issue: time to go\n", screenBuf.String())
+	if Clock() != nil {
+		t.Errorf("Clock was not restored to the default")
+	}
+}
+
+func TestQuietAndSilent(t *testing.T) {
+	screenBuf := new(bytes.Buffer)
+	logBuf := new(bytes.Buffer)
+	SetWriter(LevelAll, screenBuf, ForScreen)
+	SetWriter(LevelAll, logBuf, ForLogfile)
+	SetFlags(LevelAll, 0, ForLogfile)
+	SetThreshold(LevelInfo, ForLogfile)
+	SetExitFunc(func(val int) {})
+
+	SetQuiet(true)
+	Noteln("hidden note")
+	Infoln("hidden info")
+	MustShowln("3 files updated")
+	// must-show output at other levels and via Loggers
+	Always().Noteln("2 repos skipped")
+	WithPrefix("[repo foo] ").MustShowln("synced")
+	WithPrefix("[repo bar] ").Always().Verboseln("cloned")
+	Issueln("an issue")
+	assert.Equal(t, "3 files updated\n"+
+		"Note: 2 repos skipped\n"+
+		"[repo foo] synced\n"+
+		"[repo bar] cloned\n"+
+		"Issue: an issue\n", screenBuf.String())
+
+	// must-show output ignores the threshold too, but not silent mode
+	screenBuf.Reset()
+	SetThreshold(LevelError, ForScreen)
+	MustShowf("%d files updated\n", 4)
+	SetSilent(true)
+	MustShow("not shown\n")
+	Always().Noteln("not shown either")
+	Errorln("an error")
+	Fatalln("a fatal error")
+	assert.Equal(t, "4 files updated\nFatal: a fatal error\n", screenBuf.String())
+	assert.Equal(t, true, Quiet())
+	assert.Equal(t, true, Silent())
+	SetExitFunc(nil)
+	ResetOutPkg()
+
+	// the logfile keeps its own threshold
+	assert.Contains(t, logBuf.String(), "Note: hidden note\nhidden info\n3 files updated\n")
+	assert.Contains(t, logBuf.String(), "not shown\nNote: not shown either\nError: an error\n")
+	assert.Equal(t, false, Quiet())
+}
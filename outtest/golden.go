@@ -0,0 +1,216 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package outtest
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dvln/out"
+)
+
+// update, if set via "go test -outtest.update", causes AssertGolden() to
+// rewrite the golden files with the current output instead of comparing
+// against them (an "update" bool flag of the test itself works as well)
+var update = flag.Bool("outtest.update", false, "update outtest golden files")
+
+// stackMarker starts a stack trace in the output, see out.DefaultError()
+const stackMarker = "Stack Trace: "
+
+// Normalized returns the text written to the given target (out.ForScreen,
+// out.ForLogfile or out.ForBoth) with anything that changes from run to run
+// replaced by a placeholder so it can be compared against a golden file:
+// - the pid, date and time flag metadata, eg: "[PID] YYYY/MM/DD HH:MM:SS.UUUUUU"
+// - the directory of long file names in the flag metadata becomes "PATH"
+// - stack traces become "Stack Trace: STACK" (the trace is dropped)
+// - the log file name (see out.LogFileName()) becomes "LOGFILE"
+// The flag metadata is only replaced where the flags active for the record
+// put it (at the start of a line) and a stack trace only where it follows
+// the level prefix, other output is left as is.
+func (r *Recorder) Normalized(outputTgt int) string {
+	var buf bytes.Buffer
+	pid := "[" + strconv.Itoa(os.Getpid()) + "] "
+	for _, rec := range r.Records(outputTgt, out.LevelAll) {
+		stackStart := levelPrefix(rec) + stackMarker
+		for _, line := range strings.SplitAfter(rec.Text, "\n") {
+			line = normalizeLeader(line, rec.Flags, pid)
+			if idx := strings.Index(line, stackStart); idx != -1 {
+				// the stack trace runs to the end of the record
+				buf.WriteString(line[:idx+len(stackStart)] + "STACK\n")
+				break
+			}
+			buf.WriteString(line)
+		}
+	}
+	s := buf.String()
+	if logFile := out.LogFileName(); logFile != "" {
+		s = strings.Replace(s, logFile, "LOGFILE", -1)
+	}
+	return s
+}
+
+// levelPrefix returns the level prefix of the record with any error code
+// inserted the way the 'out' pkg does it, eg: "Issue #293: "
+func levelPrefix(rec Record) string {
+	prefix := out.Prefix(rec.Level)
+	if rec.Code > 0 && rec.Code != int(out.DefaultErrCode()) {
+		if parts := strings.Split(prefix, ":"); len(parts) == 2 {
+			prefix = parts[0] + " #" + strconv.Itoa(rec.Code) + ":" + parts[1]
+		}
+	}
+	return prefix
+}
+
+// normalizeLeader replaces the pid, date, time and long file directory in the
+// flag metadata at the start of the given line based on the flags (the layout is that of the 'out'
+// pkg, ie: "[pid] LEVEL    2016/03/04 05:06:07.123456 file.go:..."), the line
+// is returned as is if it doesn't start with that metadata
+func normalizeLeader(line string, flags int, pid string) string {
+	var buf bytes.Buffer
+	rest := line
+	if flags&out.Lpid != 0 {
+		if !strings.HasPrefix(rest, pid) {
+			return line
+		}
+		buf.WriteString("[PID] ")
+		rest = rest[len(pid):]
+	}
+	if flags&out.Llevel != 0 {
+		if len(rest) < 8 {
+			return line
+		}
+		buf.WriteString(rest[:8])
+		rest = rest[8:]
+	}
+	if flags&out.Ldate != 0 {
+		if !matchLayout(rest, "0000/00/00 ") {
+			return line
+		}
+		buf.WriteString("YYYY/MM/DD ")
+		rest = rest[11:]
+	}
+	if flags&(out.Ltime|out.Lmicroseconds) != 0 {
+		layout, placeholder := "00:00:00 ", "HH:MM:SS "
+		if flags&out.Lmicroseconds != 0 {
+			layout, placeholder = "00:00:00.000000 ", "HH:MM:SS.UUUUUU "
+		}
+		if !matchLayout(rest, layout) {
+			return line
+		}
+		buf.WriteString(placeholder)
+		rest = rest[len(layout):]
+	}
+	if flags&out.Llongfile != 0 && flags&out.Lshortfile == 0 {
+		// "/path/to/file.go:12:func   : ", the padding depends on the path
+		idx := strings.Index(rest, ".go:")
+		if idx == -1 {
+			return line
+		}
+		end := strings.Index(rest[idx:], ": ")
+		if end == -1 {
+			return line
+		}
+		end += idx
+		buf.WriteString("PATH/" + path.Base(rest[:idx+3]) + strings.TrimRight(rest[idx+3:end], " ") + ": ")
+		rest = rest[end+2:]
+	}
+	buf.WriteString(rest)
+	return buf.String()
+}
+
+// updating returns true if the golden files are to be rewritten, ie: if the
+// -outtest.update flag or an "update" bool flag of the test itself is set
+func updating() bool {
+	if *update {
+		return true
+	}
+	if f := flag.Lookup("update"); f != nil {
+		if getter, ok := f.Value.(flag.Getter); ok {
+			if val, ok := getter.Get().(bool); ok {
+				return val
+			}
+		}
+	}
+	return false
+}
+
+// matchLayout checks if s starts with the given layout where each '0' in the
+// layout matches any digit and anything else must match exactly
+func matchLayout(s string, layout string) bool {
+	if len(s) < len(layout) {
+		return false
+	}
+	for i := 0; i < len(layout); i++ {
+		if layout[i] == '0' {
+			if s[i] < '0' || s[i] > '9' {
+				return false
+			}
+		} else if s[i] != layout[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// AssertGolden compares the normalized output written to the given target
+// (see Normalized()) against the golden file testdata/<name>.golden and fails
+// the test if they differ, returns true if the assertion passed.  If the test
+// is run with the -outtest.update flag the golden file is (re)written instead.
+func (r *Recorder) AssertGolden(outputTgt int, name string) bool {
+	r.t.Helper()
+	got := r.Normalized(outputTgt)
+	goldenFile := filepath.Join("testdata", name+".golden")
+	if updating() {
+		if err := os.MkdirAll("testdata", 0755); err != nil {
+			r.t.Errorf("Unable to create golden file dir: %s", err)
+			return false
+		}
+		if err := ioutil.WriteFile(goldenFile, []byte(got), 0644); err != nil {
+			r.t.Errorf("Unable to update golden file: %s", err)
+			return false
+		}
+		return true
+	}
+	wantBytes, err := ioutil.ReadFile(goldenFile)
+	if err != nil {
+		r.t.Errorf("Unable to read golden file (use -outtest.update to create it): %s", err)
+		return false
+	}
+	want := string(wantBytes)
+	if got == want {
+		return true
+	}
+	gotLines := strings.Split(got, "\n")
+	wantLines := strings.Split(want, "\n")
+	for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+		var gotLine, wantLine string
+		if i < len(gotLines) {
+			gotLine = gotLines[i]
+		}
+		if i < len(wantLines) {
+			wantLine = wantLines[i]
+		}
+		if gotLine != wantLine || i >= len(gotLines) || i >= len(wantLines) {
+			r.t.Errorf("Output differs from golden file %s at line %d (use -outtest.update to update it):\nwant: %q\n got: %q\nfull output:\n%s", goldenFile, i+1, wantLine, gotLine, got)
+			break
+		}
+	}
+	return false
+}
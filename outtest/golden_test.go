@@ -0,0 +1,53 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package test for: out/outtest/golden.go
+//   Testing in this file focuses on normalizing output and comparing it
+//   against golden files (in testdata/)
+
+package outtest
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/dvln/out"
+	"github.com/dvln/testify/assert"
+)
+
+func TestNormalizeLeader(t *testing.T) {
+	pid := "[" + strconv.Itoa(os.Getpid()) + "] "
+	assert.Equal(t, "[PID] NOTE    YYYY/MM/DD HH:MM:SS.UUUUUU x.go:12:f: Note: hi\n",
+		normalizeLeader(pid+"NOTE    2016/03/04 05:06:07.123456 x.go:12:f: Note: hi\n", out.LlogfileFlags, pid))
+	assert.Equal(t, "HH:MM:SS Debug: hi\n", normalizeLeader("05:06:07 Debug: hi\n", out.Ltime, pid))
+	// not a leader, leave it as is
+	assert.Equal(t, "Debug: 05:06:07 hi\n", normalizeLeader("Debug: 05:06:07 hi\n", out.Ltime, pid))
+	assert.Equal(t, "[1] 2016/03/04 hi\n", normalizeLeader("[1] 2016/03/04 hi\n", out.Lpid|out.Ldate, pid))
+	assert.Equal(t, "no flags\n", normalizeLeader("no flags\n", 0, pid))
+	// long file names lose their directory (and the padding that goes with it)
+	assert.Equal(t, "PATH/x.go:12:pkg.f: Note: hi\n", normalizeLeader("/src/pkg/x.go:12:pkg.f      : Note: hi\n", out.Llongfile|out.Llongfunc, pid))
+	assert.Equal(t, "PATH/x.go:12: hi\n", normalizeLeader("/src/pkg/x.go:12     : hi\n", out.Llongfile, pid))
+}
+
+func TestGolden(t *testing.T) {
+	rec := Capture(t)
+	out.SetFlags(out.LevelAll, out.Llevel|out.Lpid|out.Ldate|out.Ltime|out.Lmicroseconds, out.ForLogfile)
+	out.Noteln("starting up")
+	out.Debugf("multi-line\ndebug output\n")
+	out.Issueln("stack at goroutine 12 frame +0x1f")
+	out.SetStackTraceConfig(out.ForScreen | out.StackTraceAllIssues)
+	out.Issueln(out.NewErr("bad ref", 293))
+	rec.AssertGolden(out.ForScreen, "screen")
+	rec.AssertGolden(out.ForLogfile, "logfile")
+}
@@ -0,0 +1,490 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package outtest helps test code that uses the 'out' package, it captures
+// all 'out' output into a Recorder that tests can examine and assert against:
+//
+//	func TestSomething(t *testing.T) {
+//		rec := outtest.Capture(t)
+//		doSomething()
+//		rec.AssertContains(out.LevelIssue, "disk")
+//		rec.AssertNoErrors()
+//	}
+//
+// While any capture is active the 'out' package starts from its default
+// settings (see out.ResetDefaults()), keeping any formatters that were set,
+// except that the screen and logfile thresholds are opened up to the Trace
+// level so all output is recorded, the previous settings (see out.Snapshot())
+// are restored once the last capture is cleaned up.  Captures can be used in
+// parallel tests, each Recorder only records the output of the goroutine of
+// its test and of the goroutines started from there (output from goroutines
+// that can't be traced back to a capturing test is only recorded if a single
+// capture is active).  Keep in mind that the 'out' settings themselves are
+// shared by the whole test binary, ie: a parallel test changing them affects
+// the others.  Note that the message, code and metadata of records are only
+// known if the formatters are left as they were when capturing started (the
+// text as written is always recorded).
+package outtest
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dvln/out"
+)
+
+// Record is a single piece of output written to the screen or the logfile
+type Record struct {
+	Target int              // out.ForScreen or out.ForLogfile
+	Level  out.Level        // output level the record was written at
+	Msg    string           // message as given to 'out' (before prefixing)
+	Text   string           // text as written (prefixes, flags, stack, etc)
+	Code   int              // error code, see out.Code()
+	Meta   out.FlagMetadata // flag metadata (time, file, line, func, etc)
+	Flags  int              // flags active for the target at the time
+}
+
+// Recorder holds the output recorded while a capture is active, see Capture()
+type Recorder struct {
+	t          testing.TB
+	mu         sync.Mutex
+	records    []Record
+	exited     bool
+	exitVal    int
+	formatters map[out.Level]out.Formatter // formatters set when capturing started
+	pending    *pendingMsg
+	seq        int64
+	lastRecord *recordPos
+}
+
+// captures is the state of the installed recording writers and formatters,
+// shared by all active captures, and the Recorders that output is routed to
+// Note: the recording writers lock mu while the 'out' pkg holds its own lock,
+// so mu must not be held while calling into the 'out' pkg
+type captures struct {
+	installMu  sync.Mutex // held while installing or restoring the 'out' settings
+	mu         sync.Mutex
+	recorders  map[uint64][]*Recorder // by the goroutine that called Capture()
+	owners     map[uint64]*Recorder   // goroutines traced back to a Recorder
+	count      int
+	saved      *out.State
+	formatters map[out.Level]out.Formatter
+}
+
+// pendingMsg is what the recording formatter learned about the most recent
+// output of a Recorder, the recording writers attach it to what is written
+type pendingMsg struct {
+	seq   int64
+	level out.Level
+	msg   string
+	code  int
+	meta  out.FlagMetadata
+	flags [2]int // screen and logfile flags
+}
+
+// recordPos identifies the last record added to a Recorder so further writes
+// for the same output (eg: stack traces) are appended to it
+type recordPos struct {
+	seq    int64
+	target int
+	level  out.Level
+	index  int
+}
+
+var levels = []out.Level{out.LevelTrace, out.LevelDebug, out.LevelVerbose, out.LevelInfo, out.LevelNote, out.LevelIssue, out.LevelError, out.LevelFatal}
+
+var active = &captures{}
+
+// Capture starts recording 'out' output for the calling test and returns the
+// Recorder, recording stops (and the 'out' settings are restored once no
+// capture is left) via t.Cleanup().  Any exit through the 'out' pkg (eg:
+// out.Fatal()) is recorded instead of exiting, see Exited().  Capture() can
+// be used by parallel tests, see the package docs.
+func Capture(t testing.TB) *Recorder {
+	t.Helper()
+	gid, _ := goroutineIDs(goroutineStack())
+	rec := &Recorder{t: t}
+	active.installMu.Lock()
+	if active.count == 0 {
+		active.saved = out.Snapshot()
+		active.install()
+	}
+	active.mu.Lock()
+	rec.formatters = active.formatters
+	if active.recorders == nil {
+		active.recorders = make(map[uint64][]*Recorder)
+	}
+	active.recorders[gid] = append(active.recorders[gid], rec)
+	active.count++
+	active.owners = nil
+	active.mu.Unlock()
+	active.installMu.Unlock()
+	t.Cleanup(func() {
+		active.installMu.Lock()
+		defer active.installMu.Unlock()
+		active.mu.Lock()
+		recs := active.recorders[gid]
+		for i, r := range recs {
+			if r == rec {
+				recs = append(recs[:i:i], recs[i+1:]...)
+				break
+			}
+		}
+		if len(recs) == 0 {
+			delete(active.recorders, gid)
+		} else {
+			active.recorders[gid] = recs
+		}
+		active.count--
+		active.owners = nil
+		last := active.count == 0
+		active.mu.Unlock()
+		if last {
+			active.saved.Restore()
+			active.saved = nil
+		}
+	})
+	return rec
+}
+
+// install points all 'out' levels at recording writers and formatters, any
+// formatters set up are kept (the recording formatters wrap them)
+func (c *captures) install() {
+	formatters := make(map[out.Level]out.Formatter)
+	for _, level := range levels {
+		formatters[level] = out.LevelFormatter(level)
+	}
+	c.mu.Lock()
+	c.formatters = formatters
+	c.mu.Unlock()
+	out.ResetDefaults()
+	for _, level := range levels {
+		out.SetWriter(level, &recWriter{level: level, target: out.ForScreen}, out.ForScreen)
+		out.SetWriter(level, &recWriter{level: level, target: out.ForLogfile}, out.ForLogfile)
+		out.SetFormatter(level, recFormatter{level: level})
+	}
+	out.SetThreshold(out.LevelTrace, out.ForScreen)
+	out.SetThreshold(out.LevelTrace, out.ForLogfile)
+	out.SetExitFunc(func(exitVal int) {
+		rec := c.recorder()
+		if rec == nil {
+			return
+		}
+		rec.mu.Lock()
+		rec.exited = true
+		rec.exitVal = exitVal
+		rec.mu.Unlock()
+	})
+}
+
+// recorder returns the Recorder that output from the calling goroutine goes
+// to, ie: that of the test goroutine it was started from, nil if none
+func (c *captures) recorder() *Recorder {
+	gid, parent := goroutineIDs(goroutineStack())
+	c.mu.Lock()
+	if rec, ok := c.lookupLocked(gid, parent); ok {
+		c.mu.Unlock()
+		return rec
+	}
+	c.mu.Unlock()
+	// the creator isn't a capturing test, trace back through its ancestors
+	// (as far as they're still running)
+	parents := goroutineParents()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var rec *Recorder
+	for id, seen := parent, map[uint64]bool{}; id != 0 && !seen[id]; id = parents[id] {
+		seen[id] = true
+		if r, ok := c.lookupLocked(id, 0); ok {
+			rec = r
+			break
+		}
+	}
+	if rec == nil && c.count == 1 {
+		for _, recs := range c.recorders {
+			rec = recs[len(recs)-1]
+		}
+	}
+	if c.owners == nil {
+		c.owners = make(map[uint64]*Recorder)
+	}
+	c.owners[gid] = rec
+	return rec
+}
+
+// lookupLocked returns the Recorder of the given goroutine (or that of the
+// goroutine that created it) if known, c.mu must be held
+func (c *captures) lookupLocked(gid, parent uint64) (*Recorder, bool) {
+	for _, id := range []uint64{gid, parent} {
+		if id == 0 {
+			continue
+		}
+		if recs := c.recorders[id]; len(recs) > 0 {
+			return recs[len(recs)-1], true
+		}
+		if rec, ok := c.owners[id]; ok {
+			return rec, true
+		}
+	}
+	return nil, false
+}
+
+// recFormatter is installed as the formatter for all levels, it notes the
+// message details for the recording writers and leaves the output as is (or
+// to the formatter the Recorder kept, if any)
+type recFormatter struct {
+	level out.Level
+}
+
+// FormatMessage records the message details and hands the message on to the
+// kept formatter (if any)
+func (f recFormatter) FormatMessage(msg string, outLevel out.Level, code int, dying bool, mdata out.FlagMetadata) (string, int, int, bool) {
+	flags := [2]int{out.Flags(outLevel, out.ForScreen), out.Flags(outLevel, out.ForLogfile)}
+	var next out.Formatter
+	if rec := active.recorder(); rec != nil {
+		rec.mu.Lock()
+		rec.seq++
+		rec.pending = &pendingMsg{seq: rec.seq, level: outLevel, msg: msg, code: code, meta: mdata, flags: flags}
+		next = rec.formatters[f.level]
+		rec.mu.Unlock()
+	} else {
+		active.mu.Lock()
+		next = active.formatters[f.level]
+		active.mu.Unlock()
+	}
+	if next != nil {
+		return next.FormatMessage(msg, outLevel, code, dying, mdata)
+	}
+	return msg, 0, 0, false
+}
+
+// recWriter is installed as the screen and logfile writer for each level,
+// what is written is added to the Recorder of the writing goroutine
+// Note: the 'out' package holds its own lock while writing so this must not
+// call back into the 'out' package
+type recWriter struct {
+	level  out.Level
+	target int
+}
+
+// Write records the given output, it never fails
+func (w *recWriter) Write(p []byte) (int, error) {
+	if rec := active.recorder(); rec != nil {
+		rec.write(w.level, w.target, p)
+	}
+	return len(p), nil
+}
+
+// write adds the given output to the records
+func (r *Recorder) write(level out.Level, target int, p []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pending := r.pending
+	if pending != nil && pending.level != level {
+		pending = nil
+	}
+	last := r.lastRecord
+	if pending != nil && last != nil && last.seq == pending.seq && last.target == target && last.level == level && last.index < len(r.records) {
+		// more output for the same message (eg: a stack trace)
+		r.records[last.index].Text += string(p)
+		return
+	}
+	rec := Record{Target: target, Level: level, Text: string(p)}
+	seq := int64(0)
+	if pending != nil {
+		rec.Msg = pending.msg
+		rec.Code = pending.code
+		rec.Meta = pending.meta
+		rec.Flags = pending.flags[0]
+		if target == out.ForLogfile {
+			rec.Flags = pending.flags[1]
+		}
+		seq = pending.seq
+	}
+	r.records = append(r.records, rec)
+	r.lastRecord = &recordPos{seq: seq, target: target, level: level, index: len(r.records) - 1}
+}
+
+// Records returns the records for the given target (out.ForScreen, out.ForLogfile
+// or out.ForBoth) and level (use out.LevelAll for all levels), in output order
+func (r *Recorder) Records(outputTgt int, level out.Level) []Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var records []Record
+	for _, rec := range r.records {
+		if rec.Target&outputTgt == 0 {
+			continue
+		}
+		if level != out.LevelAll && rec.Level != level {
+			continue
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+// String returns all of the text written to the given target (out.ForScreen
+// or out.ForLogfile) as it would have appeared there
+func (r *Recorder) String(outputTgt int) string {
+	var buf bytes.Buffer
+	for _, rec := range r.Records(outputTgt, out.LevelAll) {
+		buf.WriteString(rec.Text)
+	}
+	return buf.String()
+}
+
+// Exited returns the exit value and true if the 'out' package tried to exit
+// while recording (eg: via out.Fatal() or out.IssueExit()), else 0 and false
+func (r *Recorder) Exited() (int, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.exitVal, r.exited
+}
+
+// Reset clears all records (and any exit) recorded so far
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	r.records = nil
+	r.lastRecord = nil
+	r.exited = false
+	r.exitVal = 0
+	r.mu.Unlock()
+}
+
+// AssertContains fails the test if no record at the given level (use
+// out.LevelAll for any level) contains the given string, returns true if
+// the assertion passed
+func (r *Recorder) AssertContains(level out.Level, substr string) bool {
+	r.t.Helper()
+	for _, rec := range r.Records(out.ForBoth, level) {
+		if strings.Contains(rec.Msg, substr) || strings.Contains(rec.Text, substr) {
+			return true
+		}
+	}
+	r.t.Errorf("No %s level output contains %q, output:\n%s", level, substr, r.dump(level))
+	return false
+}
+
+// AssertNotContains fails the test if any record at the given level (use
+// out.LevelAll for any level) contains the given string, returns true if
+// the assertion passed
+func (r *Recorder) AssertNotContains(level out.Level, substr string) bool {
+	r.t.Helper()
+	for _, rec := range r.Records(out.ForBoth, level) {
+		if strings.Contains(rec.Msg, substr) || strings.Contains(rec.Text, substr) {
+			r.t.Errorf("Found %s level output containing %q, output:\n%s", level, substr, r.dump(level))
+			return false
+		}
+	}
+	return true
+}
+
+// AssertNoErrors fails the test if anything was output at the Error or Fatal
+// levels, returns true if the assertion passed
+func (r *Recorder) AssertNoErrors() bool {
+	r.t.Helper()
+	var errs []string
+	for _, rec := range r.Records(out.ForBoth, out.LevelAll) {
+		if rec.Level >= out.LevelError {
+			errs = append(errs, rec.Text)
+		}
+	}
+	if errs != nil {
+		r.t.Errorf("Unexpected error output:\n%s", strings.Join(errs, ""))
+		return false
+	}
+	return true
+}
+
+// AssertExited fails the test if the 'out' pkg did not try to exit with the
+// given exit value, returns true if the assertion passed
+func (r *Recorder) AssertExited(exitVal int) bool {
+	r.t.Helper()
+	val, exited := r.Exited()
+	if !exited {
+		r.t.Errorf("Expected an exit with value %d but no exit was attempted", exitVal)
+		return false
+	}
+	if val != exitVal {
+		r.t.Errorf("Expected an exit with value %d but found %d", exitVal, val)
+		return false
+	}
+	return true
+}
+
+// dump returns the screen and logfile text at the given level for messages
+func (r *Recorder) dump(level out.Level) string {
+	var buf bytes.Buffer
+	for _, rec := range r.Records(out.ForBoth, level) {
+		tgt := "screen"
+		if rec.Target == out.ForLogfile {
+			tgt = "logfile"
+		}
+		buf.WriteString(tgt + ": " + rec.Text)
+		if !strings.HasSuffix(rec.Text, "\n") {
+			buf.WriteString("\n")
+		}
+	}
+	return buf.String()
+}
+
+// goroutineStack returns the stack trace of the calling goroutine
+func goroutineStack() []byte {
+	return stacks(false)
+}
+
+// stacks returns the stack trace of the calling goroutine, or of all of them
+func stacks(all bool) []byte {
+	buf := make([]byte, 4096)
+	for {
+		n := runtime.Stack(buf, all)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// goroutineIDs returns the id of the goroutine with the given stack trace and
+// the id of the goroutine that created it (0 if not known)
+func goroutineIDs(stack []byte) (uint64, uint64) {
+	var gid, parent uint64
+	if fields := strings.Fields(strings.TrimPrefix(string(stack), "goroutine ")); len(fields) > 0 {
+		gid, _ = strconv.ParseUint(fields[0], 10, 64)
+	}
+	if i := bytes.LastIndex(stack, []byte("\ncreated by ")); i >= 0 {
+		line := stack[i+1:]
+		if j := bytes.IndexByte(line, '\n'); j >= 0 {
+			line = line[:j]
+		}
+		if j := bytes.LastIndex(line, []byte(" in goroutine ")); j >= 0 {
+			parent, _ = strconv.ParseUint(string(line[j+len(" in goroutine "):]), 10, 64)
+		}
+	}
+	return gid, parent
+}
+
+// goroutineParents returns the creator of each running goroutine by id
+func goroutineParents() map[uint64]uint64 {
+	parents := make(map[uint64]uint64)
+	for _, stack := range bytes.Split(stacks(true), []byte("\n\n")) {
+		if gid, parent := goroutineIDs(stack); gid != 0 && parent != 0 {
+			parents[gid] = parent
+		}
+	}
+	return parents
+}
@@ -0,0 +1,156 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package test for: out/outtest/outtest.go
+//   Testing in this file focuses on capturing 'out' output in a Recorder,
+//   checking the records and assertions and that the 'out' settings are
+//   restored once capturing is done, also for parallel captures
+
+package outtest
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/dvln/out"
+	"github.com/dvln/testify/assert"
+)
+
+// failT is used to check that assertions fail when they should
+type failT struct {
+	testing.TB
+	failed bool
+}
+
+func (f *failT) Errorf(format string, args ...interface{}) {
+	f.failed = true
+}
+
+func TestCapture(t *testing.T) {
+	t.Run("records", func(t *testing.T) {
+		rec := Capture(t)
+		out.Issuef("disk %s is full\n", "/tmp")
+		out.Noteln("all done")
+
+		screenIssues := rec.Records(out.ForScreen, out.LevelIssue)
+		assert.Equal(t, 1, len(screenIssues))
+		assert.Equal(t, "disk /tmp is full\n", screenIssues[0].Msg)
+		assert.Equal(t, "Issue: disk /tmp is full\n", screenIssues[0].Text)
+		assert.Equal(t, 100, screenIssues[0].Code)
+		logIssues := rec.Records(out.ForLogfile, out.LevelIssue)
+		assert.Equal(t, 1, len(logIssues))
+		assert.Equal(t, out.LlogfileFlags, logIssues[0].Flags)
+		assert.Equal(t, "outtest_test.go", logIssues[0].Meta.File)
+		assert.Equal(t, "Issue: disk /tmp is full\nNote: all done\n", rec.String(out.ForScreen))
+		rec.AssertContains(out.LevelIssue, "disk")
+		rec.AssertNotContains(out.LevelNote, "disk")
+		rec.AssertNoErrors()
+
+		ft := &failT{TB: t}
+		failRec := &Recorder{t: ft, records: rec.Records(out.ForBoth, out.LevelAll)}
+		failRec.AssertContains(out.LevelNote, "disk")
+		assert.Equal(t, true, ft.failed)
+		out.Error("oops\n")
+		ft.failed = false
+		rec.t = ft
+		rec.AssertNoErrors()
+		rec.t = t
+		assert.Equal(t, true, ft.failed)
+	})
+	// settings should be back as they were now that capturing is done
+	assert.Equal(t, os.Stdout, out.Writer(out.LevelInfo, out.ForScreen))
+	assert.Equal(t, out.LevelInfo, out.Threshold(out.ForScreen))
+	assert.Equal(t, nil, out.LevelFormatter(out.LevelIssue))
+}
+
+func TestCaptureExit(t *testing.T) {
+	rec := Capture(t)
+	out.IssueExit(3, "cannot continue\n")
+	rec.AssertExited(3)
+	rec.AssertContains(out.LevelIssue, "cannot continue")
+	rec.Reset()
+	_, exited := rec.Exited()
+	assert.Equal(t, false, exited)
+	assert.Equal(t, "", rec.String(out.ForBoth))
+}
+
+// upperFormatter uppercases the screen output
+type upperFormatter struct{}
+
+func (f upperFormatter) FormatMessage(msg string, outLevel out.Level, code int, dying bool, mdata out.FlagMetadata) (string, int, int, bool) {
+	return strings.ToUpper(msg), out.ForScreen, 0, false
+}
+
+func TestCaptureFormatter(t *testing.T) {
+	out.SetFormatter(out.LevelNote, upperFormatter{})
+	t.Run("kept", func(t *testing.T) {
+		rec := Capture(t)
+		done := make(chan bool)
+		go func() {
+			out.Noteln("from a goroutine")
+			done <- true
+		}()
+		<-done
+		records := rec.Records(out.ForScreen, out.LevelNote)
+		assert.Equal(t, 1, len(records))
+		assert.Equal(t, "from a goroutine\n", records[0].Msg)
+		assert.Equal(t, "Note: FROM A GOROUTINE\n", records[0].Text)
+	})
+	assert.Equal(t, upperFormatter{}, out.LevelFormatter(out.LevelNote))
+	out.ClearFormatter(out.LevelAll)
+}
+
+func TestCaptureParallel(t *testing.T) {
+	t.Run("group", func(t *testing.T) {
+		for _, name := range []string{"a", "b", "c"} {
+			name := name
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+				rec := Capture(t)
+				done := make(chan bool)
+				for i := 0; i < 20; i++ {
+					out.Issuef("%s direct %d\n", name, i)
+					go func(i int) {
+						// output from goroutines started by the test too
+						go func() {
+							out.Notef("%s nested %d\n", name, i)
+							done <- true
+						}()
+					}(i)
+					<-done
+				}
+				issues := rec.Records(out.ForScreen, out.LevelIssue)
+				notes := rec.Records(out.ForScreen, out.LevelNote)
+				assert.Equal(t, 20, len(issues))
+				assert.Equal(t, 20, len(notes))
+				for i := range issues {
+					assert.Equal(t, fmt.Sprintf("%s direct %d\n", name, i), issues[i].Msg)
+					assert.Equal(t, fmt.Sprintf("Note: %s nested %d\n", name, i), notes[i].Text)
+				}
+				if name == "b" {
+					out.IssueExit(3, "giving up\n")
+					rec.AssertExited(3)
+				} else {
+					_, exited := rec.Exited()
+					assert.Equal(t, false, exited)
+				}
+			})
+		}
+	})
+	// settings should be back as they were once all captures are done
+	assert.Equal(t, os.Stdout, out.Writer(out.LevelInfo, out.ForScreen))
+	assert.Equal(t, out.LevelInfo, out.Threshold(out.ForScreen))
+	assert.Equal(t, nil, out.LevelFormatter(out.LevelIssue))
+}
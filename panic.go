@@ -0,0 +1,139 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package out
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// recoverRepanic, if non-zero, causes RecoverAndExit() to panic again with
+// the original panic value after the panic has been dumped instead of exiting
+var recoverRepanic int32
+
+// SetRecoverRepanic can be used to have RecoverAndExit() re-panic with the
+// original panic value (after dumping it and running any defer func) instead
+// of exiting, this is mostly useful for testing
+func SetRecoverRepanic(repanic bool) {
+	var val int32
+	if repanic {
+		val = 1
+	}
+	atomic.StoreInt32(&recoverRepanic, val)
+}
+
+// RecoverRepanic returns true if RecoverAndExit() is set to re-panic instead
+// of exiting, see SetRecoverRepanic()
+func RecoverRepanic() bool {
+	return atomic.LoadInt32(&recoverRepanic) != 0
+}
+
+// RecoverAndExit is used to route a panic through the 'out' pkg so it is
+// treated like any other Fatal() error, it must be deferred directly:
+//
+//	defer out.RecoverAndExit()
+//
+// If a panic is in progress it is recovered and the panic value, along with
+// the stack of the panicking goroutine, is dumped as a detailed error at the
+// Fatal level (honoring the stack trace config), any defer func is run and
+// we exit with the given exit value (or the error exit value if none given,
+// see SetErrorExitVal()).  If SetRecoverRepanic(true) is in effect we will
+// panic again with the original value instead of exiting.  If there is no
+// panic in progress this does nothing.
+func RecoverAndExit(exitVal ...int) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	myExitVal := int(atomic.LoadInt32(&errorExitVal))
+	if exitVal != nil {
+		myExitVal = exitVal[0]
+	}
+	detErr := panicError(r)
+
+	// Note: we're called directly from the panic so we dump straight via the
+	// Fatal level stringOutput() so the call depth maps to the panic location
+	_, err := FATAL.stringOutput(detErr.Error()+"\n", true, myExitVal, outputOpts{}, detErr)
+	if err != nil {
+		mutex.Lock()
+		{
+			fmt.Fprintf(os.Stderr, "%s", err)
+		}
+		mutex.Unlock()
+	}
+	if RecoverRepanic() {
+		mutex.RLock()
+		dFunc := deferFunc
+		mutex.RUnlock()
+		if dFunc != nil {
+			dFunc(myExitVal)
+		}
+		panic(r)
+	}
+	terminate(myExitVal, nil)
+}
+
+// Go runs the given func in a new goroutine with RecoverAndExit() deferred so
+// that any panic in that goroutine is dumped and exits as described there
+// (the error exit value is used as the exit value)
+func Go(f func()) {
+	go func() {
+		defer RecoverAndExit()
+		f()
+	}()
+}
+
+// panicError converts a recovered panic value into a detailed error at the
+// Fatal level, if the value is an error it is wrapped (so the original error
+// is available via errors.Is() and such), the stack of the panicking goroutine
+// is used for the stack trace
+func panicError(r interface{}) DetailedError {
+	detErr := &BaseError{
+		msg:    fmt.Sprintf("panic: %v", r),
+		lvlOut: FATAL,
+	}
+	if err, ok := r.(error); ok {
+		detErr.msg = "panic"
+		detErr.inner = err
+	}
+	detErr.stack, detErr.context = stackTrace(0)
+	detErr.stack = panicStack(detErr.stack)
+	return detErr
+}
+
+// panicStack takes a stack trace gathered while handling a panic and removes
+// the frames up to and including the panic() call itself so the trace starts
+// at the location of the panic, the goroutine header line is kept as is
+func panicStack(stack string) string {
+	idx := strings.LastIndex(stack, "\npanic(")
+	if idx == -1 {
+		return stack
+	}
+	header := stack
+	if hdrIdx := strings.Index(stack, "\n"); hdrIdx != -1 {
+		header = stack[:hdrIdx]
+	}
+	// skip the panic() func line and the file/line that follows it
+	rest := stack[idx+1:]
+	for i := 0; i < 2; i++ {
+		nlIdx := strings.Index(rest, "\n")
+		if nlIdx == -1 {
+			return header
+		}
+		rest = rest[nlIdx+1:]
+	}
+	return header + "\n" + rest
+}
@@ -0,0 +1,96 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package test for: out/panic.go
+//   Testing in this file focuses on routing panics through the 'out' pkg
+//   via RecoverAndExit() and Go(), insuring the panic shows up at the Fatal
+//   level with the stack of the panic location
+
+package out
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dvln/testify/assert"
+)
+
+func panicky() {
+	defer RecoverAndExit(3)
+	panic("something bad happened")
+}
+
+func TestRecoverAndExit(t *testing.T) {
+	screenBuf := new(bytes.Buffer)
+	logfileBuf := new(bytes.Buffer)
+	SetWriter(LevelAll, screenBuf, ForScreen)
+	SetWriter(LevelAll, logfileBuf, ForLogfile)
+	SetThreshold(LevelInfo, ForLogfile)
+	SetRecoverRepanic(true)
+	deferExitVal := 0
+	SetDeferFunc(func(exitVal int) { deferExitVal = exitVal })
+
+	var repanicked interface{}
+	func() {
+		defer func() { repanicked = recover() }()
+		panicky()
+	}()
+
+	SetRecoverRepanic(false)
+	SetDeferFunc(nil)
+	ResetOutPkg()
+
+	assert.Equal(t, "something bad happened", repanicked)
+	assert.Equal(t, 3, deferExitVal)
+	screenStr := screenBuf.String()
+	logfileStr := logfileBuf.String()
+	assert.Contains(t, screenStr, "Fatal: panic: something bad happened\n")
+	assert.NotContains(t, screenStr, "Stack Trace:")
+	assert.Contains(t, logfileStr, "panic: something bad happened")
+	assert.Contains(t, logfileStr, "Stack Trace:")
+	// the stack should start at the panic location, not in RecoverAndExit()
+	assert.Contains(t, logfileStr, "out.panicky(")
+	assert.NotContains(t, logfileStr, "out.RecoverAndExit(")
+	assert.NotContains(t, logfileStr, "\npanic(")
+}
+
+func TestGoRecover(t *testing.T) {
+	screenBuf := new(bytes.Buffer)
+	SetWriter(LevelAll, screenBuf, ForScreen)
+	Discard(ForLogfile)
+	SetErrorExitVal(5)
+	deferExitVal := 0
+	SetDeferFunc(func(exitVal int) { deferExitVal = exitVal })
+	// the exit is recorded (and signals the test) instead of exiting, this
+	// is only called once the defer func is done
+	done := make(chan int)
+	SetExitFunc(func(exitVal int) { done <- exitVal })
+	defer SetExitFunc(nil)
+
+	origErr := errors.New("goroutine failure")
+	Go(func() { panic(origErr) })
+	exitVal := <-done
+
+	SetDeferFunc(nil)
+	SetErrorExitVal(-1)
+	ResetOutPkg()
+
+	assert.Equal(t, 5, exitVal)
+	assert.Equal(t, 5, deferExitVal)
+	screenStr := screenBuf.String()
+	assert.Contains(t, screenStr, "Fatal: panic\n")
+	assert.Contains(t, screenStr, "goroutine failure")
+	assert.Equal(t, 1, strings.Count(screenStr, "goroutine failure"))
+}
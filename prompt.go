@@ -0,0 +1,221 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package out
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// passwordMask is what is logged in place of a password typed in
+const passwordMask = "********"
+
+var (
+	// promptMu makes sure only one prompt at a time reads from the input
+	promptMu sync.Mutex
+
+	// promptReader is where answers to prompts are read from (protected by
+	// the 'out' mutex), promptBuf buffers it (protected by promptMu)
+	promptReader io.Reader = os.Stdin
+	promptBuf    *bufio.Reader
+	promptBufFor io.Reader
+)
+
+// PromptReader returns where the answers to prompts are read from, see
+// SetPromptReader()
+func PromptReader() io.Reader {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return promptReader
+}
+
+// SetPromptReader sets where the answers to prompts (see Prompt()) are read
+// from, os.Stdin by default (also if nil is given), eg: a strings.Reader to
+// script the answers in a test
+func SetPromptReader(r io.Reader) {
+	if r == nil {
+		r = os.Stdin
+	}
+	mutex.Lock()
+	{
+		promptReader = r
+	}
+	mutex.Unlock()
+}
+
+// Prompt asks the user for input, the question (formatted as with
+// fmt.Sprintf()) is shown as Note output (so prefixed with "Note: " by
+// default, even in quiet mode) and a line of input is read, eg:
+//
+//	name, err := out.Prompt("Project name: ")
+//
+// The answer is returned without the trailing newline, the error is io.EOF
+// if the input ends before a line is typed in.  The newline tracking is
+// updated once the answer is read so any output that follows is prefixed
+// as usual.  The question and the answer are also written to the log file
+// (if Note output is logged) so a session can be reproduced, see Password()
+// to keep the answer out of the log file.
+func Prompt(format string, v ...interface{}) (string, error) {
+	promptMu.Lock()
+	defer promptMu.Unlock()
+	NOTE.outputf(false, 0, outputOpts{mustShow: true}, format, v...)
+	answer, echoed, err := readAnswer(false)
+	promptDone(answer, echoed, err)
+	return answer, err
+}
+
+// Confirm asks the user a yes or no question, " [Y/n] " (or " [y/N] ") is
+// added to the question (see Prompt()) and an empty answer gives the
+// default, eg:
+//
+//	if ok, _ := out.Confirm(false, "Remove %d files?", len(files)); ok {
+//
+// The question is asked again until the answer is yes or no (or y or n, in
+// any case), if the input ends the default is returned with io.EOF.
+func Confirm(dflt bool, format string, v ...interface{}) (bool, error) {
+	promptMu.Lock()
+	defer promptMu.Unlock()
+	choices := " [y/N] "
+	if dflt {
+		choices = " [Y/n] "
+	}
+	for {
+		NOTE.outputf(false, 0, outputOpts{mustShow: true}, "%s", fmt.Sprintf(format, v...)+choices)
+		answer, echoed, err := readAnswer(false)
+		promptDone(answer, echoed, err)
+		if err != nil {
+			return dflt, err
+		}
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "":
+			return dflt, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+	}
+}
+
+// Select asks the user to pick one of the options, these are listed (as
+// Note output, numbered from 1) before the question (see Prompt()), eg:
+//
+//	idx, err := out.Select([]string{"dev", "staging", "prod"}, "Deploy to:")
+//
+//	Note: Deploy to:
+//	Note:   1) dev
+//	Note:   2) staging
+//	Note:   3) prod
+//	Note: Enter 1-3: 2
+//
+// The answer may be the number or the text of an option (in any case), the
+// question is asked again until a valid option is given.  The index of the
+// option picked is returned, -1 with io.EOF if the input ends (or with an
+// error, without asking, if there are no options to pick from).
+func Select(options []string, format string, v ...interface{}) (int, error) {
+	if len(options) == 0 {
+		return -1, fmt.Errorf("no options to select from for %q", fmt.Sprintf(format, v...))
+	}
+	promptMu.Lock()
+	defer promptMu.Unlock()
+	var list []string
+	for i, option := range options {
+		list = append(list, fmt.Sprintf("  %d) %s", i+1, option))
+	}
+	for {
+		NOTE.outputf(false, 0, outputOpts{mustShow: true}, "%s", fmt.Sprintf(format, v...)+"\n"+strings.Join(list, "\n")+fmt.Sprintf("\nEnter 1-%d: ", len(options)))
+		answer, echoed, err := readAnswer(false)
+		promptDone(answer, echoed, err)
+		if err != nil {
+			return -1, err
+		}
+		answer = strings.TrimSpace(answer)
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(options) {
+			return n - 1, nil
+		}
+		for i, option := range options {
+			if strings.EqualFold(answer, option) {
+				return i, nil
+			}
+		}
+	}
+}
+
+// Password asks the user for a password or other secret (see Prompt()),
+// if the input is a terminal what is typed in isn't echoed and the log file
+// only gets "********" as the answer
+func Password(format string, v ...interface{}) (string, error) {
+	promptMu.Lock()
+	defer promptMu.Unlock()
+	NOTE.outputf(false, 0, outputOpts{mustShow: true}, format, v...)
+	answer, echoed, err := readAnswer(true)
+	promptDone(passwordMask, echoed, err)
+	return answer, err
+}
+
+// readAnswer reads a line from the prompt reader, without echo for secrets
+// if the reader is a terminal, it returns the line (without the newline),
+// if the line was echoed by the terminal and any error, promptMu must be held
+func readAnswer(secret bool) (string, bool, error) {
+	// the question must be on the screen before the answer is typed in
+	if group := currentGroup(); group != nil {
+		group.Flush()
+	}
+	r := PromptReader()
+	if promptBuf == nil || promptBufFor != r {
+		promptBuf, promptBufFor = bufio.NewReader(r), r
+	}
+	echoed := false
+	if file, ok := r.(*os.File); ok && isTerminal(file) {
+		echoed = true
+		if secret && setEcho(file, false) == nil {
+			echoed = false
+			defer setEcho(file, true)
+		}
+	}
+	line, err := promptBuf.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil // a last line without a newline
+	}
+	return strings.TrimRight(line, "\r\n"), echoed, err
+}
+
+// promptDone finishes a prompt, the answer is written to the log file after
+// the question and, unless the terminal echoed the answer (and newline), a
+// newline goes to the screen to end the line the question is on
+func promptDone(answer string, echoed bool, err error) {
+	if err == nil {
+		NOTE.stringOutput(answer+"\n", false, 0, outputOpts{logfileOnly: true})
+	} else {
+		NOTE.stringOutput("\n", false, 0, outputOpts{logfileOnly: true})
+	}
+	group := currentGroup()
+	mutex.Lock()
+	if group != nil {
+		group.newline = true
+	}
+	if !echoed && !screenNewline {
+		NOTE.mu.RLock()
+		w := NOTE.screenHndl
+		NOTE.mu.RUnlock()
+		screenWriteLocked(w, []byte("\n"))
+	}
+	screenNewline = true
+	mutex.Unlock()
+}
@@ -0,0 +1,83 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package test for: out/prompt.go
+//   Testing in this file focuses on prompting for input, the newline tracking
+//   around prompts and logging the questions and answers
+
+package out
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/dvln/testify/assert"
+)
+
+func TestPrompt(t *testing.T) {
+	screenBuf := new(bytes.Buffer)
+	logBuf := new(bytes.Buffer)
+	SetWriter(LevelAll, screenBuf, ForScreen)
+	SetWriter(LevelAll, logBuf, ForLogfile)
+	SetThreshold(LevelInfo, ForLogfile)
+	SetFlags(LevelAll, 0, ForLogfile)
+	SetPromptReader(strings.NewReader("widget\nmaybe\n\nbogus\nStaging\nhunter2\n"))
+
+	name, err := Prompt("Project name: ")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "widget", name)
+	Noteln("creating it")
+	assert.Equal(t, "Note: Project name: \nNote: creating it\n", screenBuf.String())
+	assert.Equal(t, "Note: Project name: widget\nNote: creating it\n", logBuf.String())
+
+	// invalid answers ask again, an empty answer is the default
+	screenBuf.Reset()
+	logBuf.Reset()
+	ok, err := Confirm(true, "Remove %d files?", 3)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, "Note: Remove 3 files? [Y/n] \nNote: Remove 3 files? [Y/n] \n", screenBuf.String())
+	assert.Equal(t, "Note: Remove 3 files? [Y/n] maybe\nNote: Remove 3 files? [Y/n] \n", logBuf.String())
+
+	logBuf.Reset()
+	idx, err := Select([]string{"dev", "staging"}, "Deploy to:")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, idx)
+	options := "Note: Deploy to:\nNote:   1) dev\nNote:   2) staging\n"
+	assert.Equal(t, options+"Note: Enter 1-2: bogus\n"+options+"Note: Enter 1-2: Staging\n", logBuf.String())
+
+	// nothing to pick from, nothing is asked (or read)
+	logBuf.Reset()
+	idx, err = Select(nil, "Deploy to:")
+	assert.Equal(t, -1, idx)
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, "", logBuf.String())
+
+	// passwords are masked in the log file
+	logBuf.Reset()
+	password, err := Password("Password: ")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "hunter2", password)
+	assert.Equal(t, "Note: Password: ********\n", logBuf.String())
+	assert.NotContains(t, screenBuf.String(), "hunter2")
+
+	// the input running out is reported, prompts show even in quiet mode
+	screenBuf.Reset()
+	SetQuiet(true)
+	_, err = Prompt("Anything else? ")
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, "Note: Anything else? \n", screenBuf.String())
+	ResetOutPkg()
+}
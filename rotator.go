@@ -2,12 +2,14 @@
 //   http://stackoverflow.com/questions/28796021/how-can-i-log-in-golang-to-a-file-with-log-rotation
 // Frankly I would recommend using Nate Finch's lumberjack in most cases but
 // if looking for  a "builtin" rotator feel free to modify/use/adjust this.
-// Note that currently the user must decide when to call Rotate() below.
+// Note that the user must decide when to call Rotate() below unless a max
+// size is set via SetMaxSize() (then writes rotate the file when it fills).
 
 package out
 
 import (
 	"os"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -17,6 +19,8 @@ type RotateWriter struct {
 	lock     sync.Mutex
 	filename string // should be set to the actual filename
 	fp       *os.File
+	maxSize  int64 // rotate when a write would go past this size (0: never)
+	size     int64 // bytes written to the current file
 }
 
 // NewRotateWr makes a new RotateWriter.  I would tend to recommend using lumberjack
@@ -32,17 +36,53 @@ func NewRotateWr(filename string) *RotateWriter {
 }
 
 // Write satisfies the io.Writer interface.
+// If a max size is set and the write would go past it the file is rotated
+// first (a write larger than the max size still lands in a single file).
 func (w *RotateWriter) Write(output []byte) (int, error) {
 	w.lock.Lock()
 	defer w.lock.Unlock()
-	return w.fp.Write(output)
+	if w.maxSize > 0 && w.size > 0 && w.size+int64(len(output)) > w.maxSize {
+		if err := w.rotate(now()); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.fp.Write(output)
+	w.size += int64(n)
+	return n, err
+}
+
+// Filename returns the name of the file being written to
+func (w *RotateWriter) Filename() string {
+	return w.filename
+}
+
+// MaxSize returns the size (in bytes) at which the file is rotated, 0 if
+// the file is only rotated via Rotate()
+func (w *RotateWriter) MaxSize() int64 {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.maxSize
+}
+
+// SetMaxSize sets the size (in bytes) at which writes rotate the file, the
+// old file is renamed with a timestamp suffix as Rotate() does, 0 turns off
+// automatic rotation
+func (w *RotateWriter) SetMaxSize(maxSize int64) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.maxSize = maxSize
 }
 
 // Rotate performs the actual act of rotating and reopening file.
 func (w *RotateWriter) Rotate() error {
 	w.lock.Lock()
 	defer w.lock.Unlock()
+	return w.rotate(now())
+}
 
+// rotate is Rotate() without the locking, the lock must be held, the old
+// file is renamed using the given time stamp
+func (w *RotateWriter) rotate(stamp time.Time) error {
 	var err error
 	// Close existing file if open
 	if w.fp != nil {
@@ -55,7 +95,7 @@ func (w *RotateWriter) Rotate() error {
 	// Rename dest file if it already exists
 	_, err = os.Stat(w.filename)
 	if err == nil {
-		err = os.Rename(w.filename, w.filename+"."+time.Now().Format(time.RFC3339))
+		err = os.Rename(w.filename, backupName(w.filename, stamp))
 		if err != nil {
 			return err
 		}
@@ -63,5 +103,56 @@ func (w *RotateWriter) Rotate() error {
 
 	// Create a file.
 	w.fp, err = os.Create(w.filename)
+	w.size = 0
 	return err
 }
+
+// backupName returns the name to rename the file to when rotating it, ie:
+// "<filename>.<RFC3339 stamp>", if a backup of that name already exists (eg:
+// two rotations within a second) a ".<n>" sequence suffix is added
+func backupName(filename string, stamp time.Time) string {
+	name := filename + "." + stamp.Format(time.RFC3339)
+	backup := name
+	for seq := 1; ; seq++ {
+		if _, err := os.Lstat(backup); os.IsNotExist(err) {
+			return backup
+		}
+		backup = name + "." + strconv.Itoa(seq)
+	}
+}
+
+// reopen closes and reopens the file for appending (without rotating it),
+// for when the file has been moved away by something else
+func (w *RotateWriter) reopen() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	fp, err := os.OpenFile(w.filename, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+	info, err := fp.Stat()
+	if err != nil {
+		fp.Close()
+		return err
+	}
+	if w.fp != nil {
+		w.fp.Close()
+	}
+	w.fp, w.size = fp, info.Size()
+	return nil
+}
+
+// openRotateWriter opens the named file for appending (creating it if needed)
+// as a RotateWriter that rotates the file when it reaches the max size
+func openRotateWriter(filename string, maxSize int64) (*RotateWriter, error) {
+	fp, err := os.OpenFile(filename, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, err
+	}
+	info, err := fp.Stat()
+	if err != nil {
+		fp.Close()
+		return nil, err
+	}
+	return &RotateWriter{filename: filename, fp: fp, maxSize: maxSize, size: info.Size()}, nil
+}
@@ -0,0 +1,192 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package out
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// sectionIndent is the screen indentation of each level of section nesting
+const sectionIndent = "  "
+
+var (
+	// sectionMu protects the sections of each goroutine
+	sectionMu sync.Mutex
+
+	// sections holds the (nested) open sections of each goroutine by id
+	sections = make(map[uint64][]*SectionScope)
+
+	// sectionGoroutines is the number of goroutines with open sections, so
+	// output can skip looking for sections (atomic)
+	sectionGoroutines int32
+)
+
+// SectionScope is an open section of output, see Section()
+type SectionScope struct {
+	title string
+	path  string // the titles of the enclosing sections and this one
+	depth int    // 1 for a top level section
+	start time.Time
+}
+
+// Section starts a section of output with the given title, the title is
+// shown (at the Info level) and any output from the same goroutine is then
+// indented on the screen until the section ends, eg:
+//
+//	s := out.Section("Build")
+//	defer s.End()
+//	out.Println("Compiling")   // "  Compiling"
+//	out.Issueln("No tests")    // "  Issue: No tests"
+//
+// Sections can be nested, each adds another level of indentation.  In the
+// log file the section path is put in front of the output instead of the
+// indentation (eg: "[Build/Test] Issue: No tests") and structured formats
+// get a "section" field.  Other goroutines working for the section can use
+// Join() to have their output in the section as well.
+func Section(title string) *SectionScope {
+	INFO.outputf(false, 0, outputOpts{}, "%s\n", title)
+	s := &SectionScope{title: title, path: title, depth: 1, start: now()}
+	gid := goroutineID()
+	sectionMu.Lock()
+	stack := sections[gid]
+	if len(stack) != 0 {
+		parent := stack[len(stack)-1]
+		s.path = parent.path + "/" + title
+		s.depth = parent.depth + 1
+	}
+	pushSection(gid, s)
+	sectionMu.Unlock()
+	return s
+}
+
+// End ends the section (and any sections within it that weren't ended) for
+// the calling goroutine and logs how long the section took (at the Verbose
+// level, eg: "Build done (1.52s)"), ending a section again does nothing
+func (s *SectionScope) End() {
+	gid := goroutineID()
+	sectionMu.Lock()
+	ended := popSection(gid, s)
+	sectionMu.Unlock()
+	if ended {
+		VERBOSE.outputf(false, 0, outputOpts{}, "%s done (%s)\n", s.title, now().Sub(s.start).Round(time.Millisecond))
+	}
+}
+
+// Join puts the output of the calling goroutine in the section too (eg: for
+// a worker started within the section), call the returned func when done:
+//
+//	go func() {
+//		defer s.Join()()
+//		...
+//	}()
+func (s *SectionScope) Join() func() {
+	gid := goroutineID()
+	sectionMu.Lock()
+	pushSection(gid, s)
+	sectionMu.Unlock()
+	return func() {
+		sectionMu.Lock()
+		popSection(gid, s)
+		sectionMu.Unlock()
+	}
+}
+
+// Path returns the section path, ie: the titles of the enclosing sections
+// and this one separated by slashes (eg: "Build/Test")
+func (s *SectionScope) Path() string {
+	return s.path
+}
+
+// pushSection adds the section to the goroutine's open sections, sectionMu
+// must be held
+func pushSection(gid uint64, s *SectionScope) {
+	if len(sections[gid]) == 0 {
+		atomic.AddInt32(&sectionGoroutines, 1)
+	}
+	sections[gid] = append(sections[gid], s)
+}
+
+// popSection removes the section and any sections within it from the
+// goroutine's open sections, returns false if it wasn't open, sectionMu
+// must be held
+func popSection(gid uint64, s *SectionScope) bool {
+	stack := sections[gid]
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i] != s {
+			continue
+		}
+		if i == 0 {
+			delete(sections, gid)
+			atomic.AddInt32(&sectionGoroutines, -1)
+		} else {
+			sections[gid] = stack[:i]
+		}
+		return true
+	}
+	return false
+}
+
+// currentSection returns the innermost open section of the calling
+// goroutine, nil if none (cheap if no goroutine has open sections)
+func currentSection() *SectionScope {
+	if atomic.LoadInt32(&sectionGoroutines) == 0 {
+		return nil
+	}
+	return goroutineSection(goroutineID())
+}
+
+// goroutineSection returns the innermost open section of the goroutine with
+// the given id, nil if none
+func goroutineSection(gid uint64) *SectionScope {
+	sectionMu.Lock()
+	defer sectionMu.Unlock()
+	stack := sections[gid]
+	if len(stack) == 0 {
+		return nil
+	}
+	return stack[len(stack)-1]
+}
+
+// sectionPrefix returns what goes in front of the level prefix for output
+// within the section, indentation for the screen or the section path for
+// the log file
+func (s *SectionScope) sectionPrefix(outputTgt int) string {
+	if outputTgt&ForScreen != 0 {
+		return strings.Repeat(sectionIndent, s.depth)
+	}
+	return "[" + s.path + "] "
+}
+
+// goroutineID returns the id of the calling goroutine, Go doesn't expose it
+// so it's taken from the header of the goroutine's stack trace (ie:
+// "goroutine 18 [running]:"), 0 if that fails
+func goroutineID() uint64 {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+	fields := bytes.Fields(bytes.TrimPrefix(buf[:n], []byte("goroutine ")))
+	if len(fields) == 0 {
+		return 0
+	}
+	id, err := strconv.ParseUint(string(fields[0]), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
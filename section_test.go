@@ -0,0 +1,97 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package test for: out/section.go
+//   Testing in this file focuses on sections of output, their indentation on
+//   the screen, the section path in the log file and per goroutine scoping
+
+package out
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dvln/testify/assert"
+)
+
+func TestSection(t *testing.T) {
+	screenBuf := new(bytes.Buffer)
+	logBuf := new(bytes.Buffer)
+	SetWriter(LevelAll, screenBuf, ForScreen)
+	SetWriter(LevelAll, logBuf, ForLogfile)
+	SetThreshold(LevelVerbose, ForLogfile)
+	SetFlags(LevelAll, 0, ForLogfile)
+	clk := &fakeClock{t: time.Date(2016, 3, 1, 12, 0, 0, 0, time.UTC)}
+	SetClock(clk.now)
+
+	build := Section("Build")
+	Println("Compiling")
+	test := Section("Test")
+	Issueln("No tests found\nin pkg x")
+	clk.t = clk.t.Add(1500 * time.Millisecond)
+	test.End()
+	test.End()
+	Println("Linking")
+	build.End()
+	Println("Done")
+	assert.Equal(t, "Build\n"+
+		"  Compiling\n"+
+		"  Test\n"+
+		"    Issue: No tests found\n"+
+		"    Issue: in pkg x\n"+
+		"  Linking\n"+
+		"Done\n", screenBuf.String())
+	assert.Equal(t, "Build\n"+
+		"[Build] Compiling\n"+
+		"[Build] Test\n"+
+		"[Build/Test] Issue: No tests found\n"+
+		"[Build/Test] Issue: in pkg x\n"+
+		"[Build] Test done (1.5s)\n"+
+		"[Build] Linking\n"+
+		"Build done (1.5s)\n"+
+		"Done\n", logBuf.String())
+	assert.Equal(t, "Build/Test", test.Path())
+
+	// other goroutines aren't in the section unless they join it
+	screenBuf.Reset()
+	deploy := Section("Deploy")
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		Println("elsewhere")
+	}()
+	go func() {
+		defer wg.Done()
+		defer deploy.Join()()
+		Println("worker")
+	}()
+	wg.Wait()
+	deploy.End()
+	assert.Contains(t, screenBuf.String(), "\nelsewhere\n")
+	assert.Contains(t, screenBuf.String(), "\n  worker\n")
+	assert.Equal(t, (*SectionScope)(nil), currentSection())
+
+	// structured formats get the section as a field
+	logBuf.Reset()
+	SetOutputFormat(FormatLogfmt, ForLogfile)
+	s := Section("Package")
+	Noteln("zipped")
+	s.End()
+	assert.Contains(t, logBuf.String(), `level=NOTE msg=zipped file=section_test.go`)
+	assert.Contains(t, logBuf.String(), `func=github.com/dvln/out.TestSection pid=`)
+	assert.Contains(t, logBuf.String(), " section=Package\n")
+	ResetOutPkg()
+}
@@ -0,0 +1,411 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package out
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// State is a snapshot of all of the 'out' package settings, see Snapshot(),
+// it can be restored via Restore() and dumped as JSON (for debugging)
+type State struct {
+	levels              []levelState
+	screenThreshold     Level
+	logThreshold        Level
+	logFileName         string
+	screenNewline       bool
+	logfileNewline      bool
+	quiet               bool
+	silent              bool
+	colorConfig         int
+	wrapWidth           int
+	statusInterval      time.Duration
+	groupBufferSize     int
+	promptReader        io.Reader
+	stackTraceConfig    int
+	screenFormat        string
+	logfileFormat       string
+	debugScope          string
+	deferFunc           func(exitVal int)
+	exitFunc            func(exitVal int)
+	clock               func() time.Time
+	callDepth           int32
+	errorExitVal        int32
+	defaultErrCode      int32
+	shortFileNameLength int32
+	longFileNameLength  int32
+	shortFuncNameLength int32
+	longFuncNameLength  int32
+}
+
+// levelState holds the settings of a single output level within a State
+type levelState struct {
+	level       Level
+	prefix      string
+	screenHndl  io.Writer
+	screenFlags int
+	logfileHndl io.Writer
+	logFlags    int
+	formatter   Formatter
+	prefixColor string
+	metaColor   string
+	wrap        bool
+}
+
+// defaultState is the snapshot of the starting settings, see ResetDefaults()
+var defaultState *State
+
+func init() {
+	defaultState = Snapshot()
+}
+
+// Snapshot returns the current settings of the 'out' package, ie: all of the
+// package wide and per level settings.  Use Restore() on the State to put
+// them back, eg:
+//
+//	snap := out.Snapshot()
+//	defer snap.Restore()
+//	out.Discard(out.ForScreen)
+//	...
+func Snapshot() *State {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return snapshotLocked()
+}
+
+// snapshotLocked is Snapshot() without the locking, the mutex must be held
+func snapshotLocked() *State {
+	s := &State{
+		screenThreshold:  screenThreshold,
+		logThreshold:     logThreshold,
+		logFileName:      logFileName,
+		screenNewline:    screenNewline,
+		logfileNewline:   logfileNewline,
+		quiet:            quiet,
+		silent:           silent,
+		colorConfig:      colorConfig,
+		wrapWidth:        wrapWidth,
+		statusInterval:   statusInterval,
+		groupBufferSize:  groupBufferSize,
+		promptReader:     promptReader,
+		stackTraceConfig: stackTraceConfig,
+		screenFormat:     screenFormat,
+		logfileFormat:    logfileFormat,
+		debugScope:       debugScope,
+		deferFunc:        deferFunc,
+		exitFunc:         exitFunc,
+	}
+	for _, o := range outputters {
+		o.mu.RLock()
+		s.levels = append(s.levels, levelState{
+			level:       o.level,
+			prefix:      o.prefix,
+			screenHndl:  o.screenHndl,
+			screenFlags: o.screenFlags,
+			logfileHndl: o.logfileHndl,
+			logFlags:    o.logFlags,
+			formatter:   o.formatter,
+			prefixColor: o.prefixColor,
+			metaColor:   o.metaColor,
+			wrap:        o.wrap,
+		})
+		o.mu.RUnlock()
+	}
+	s.clock = Clock()
+	s.callDepth = atomic.LoadInt32(&callDepth)
+	s.errorExitVal = atomic.LoadInt32(&errorExitVal)
+	s.defaultErrCode = atomic.LoadInt32(&defaultErrCode)
+	s.shortFileNameLength = atomic.LoadInt32(&shortFileNameLength)
+	s.longFileNameLength = atomic.LoadInt32(&longFileNameLength)
+	s.shortFuncNameLength = atomic.LoadInt32(&shortFuncNameLength)
+	s.longFuncNameLength = atomic.LoadInt32(&longFuncNameLength)
+	return s
+}
+
+// Restore puts back all of the 'out' package settings from the State, this
+// is done while holding the 'out' locks so no output sees partial settings
+func (s *State) Restore() {
+	mutex.Lock()
+	defer mutex.Unlock()
+	s.restoreLocked()
+}
+
+// restoreLocked is Restore() without taking the mutex, it must be held (the
+// level locks are taken here)
+func (s *State) restoreLocked() {
+	resetTerminals()
+	for _, o := range outputters {
+		o.mu.Lock()
+	}
+	for i, o := range outputters {
+		l := &s.levels[i]
+		o.prefix = l.prefix
+		o.screenHndl = l.screenHndl
+		o.screenFlags = l.screenFlags
+		o.logfileHndl = l.logfileHndl
+		o.logFlags = l.logFlags
+		o.formatter = l.formatter
+		o.prefixColor = l.prefixColor
+		o.metaColor = l.metaColor
+		o.wrap = l.wrap
+	}
+	screenThreshold = s.screenThreshold
+	logThreshold = s.logThreshold
+	logFileName = s.logFileName
+	screenNewline = s.screenNewline
+	logfileNewline = s.logfileNewline
+	quiet = s.quiet
+	silent = s.silent
+	colorConfig = s.colorConfig
+	wrapWidth = s.wrapWidth
+	statusInterval = s.statusInterval
+	groupBufferSize = s.groupBufferSize
+	promptReader = s.promptReader
+	stackTraceConfig = s.stackTraceConfig
+	screenFormat = s.screenFormat
+	logfileFormat = s.logfileFormat
+	debugScope = s.debugScope
+	deferFunc = s.deferFunc
+	exitFunc = s.exitFunc
+	clockMu.Lock()
+	clock = s.clock
+	clockMu.Unlock()
+	atomic.StoreInt32(&callDepth, s.callDepth)
+	atomic.StoreInt32(&errorExitVal, s.errorExitVal)
+	atomic.StoreInt32(&defaultErrCode, s.defaultErrCode)
+	atomic.StoreInt32(&shortFileNameLength, s.shortFileNameLength)
+	atomic.StoreInt32(&longFileNameLength, s.longFileNameLength)
+	atomic.StoreInt32(&shortFuncNameLength, s.shortFuncNameLength)
+	atomic.StoreInt32(&longFuncNameLength, s.longFuncNameLength)
+	for _, o := range outputters {
+		o.mu.Unlock()
+	}
+}
+
+// clone returns a copy of the State that can be changed independently
+func (s *State) clone() *State {
+	c := *s
+	c.levels = append([]levelState(nil), s.levels...)
+	return &c
+}
+
+// ResetDefaults restores the 'out' package settings to the starting defaults,
+// ie: everything a Snapshot() holds (see there), this is mostly of use for
+// test suites that adjust the 'out' settings.
+func ResetDefaults() {
+	defaultState.Restore()
+}
+
+// levelStateJSON is the JSON form of the settings of an output level
+type levelStateJSON struct {
+	Prefix        string   `json:"prefix"`
+	ScreenWriter  string   `json:"screenWriter"`
+	ScreenFlags   []string `json:"screenFlags"`
+	LogfileWriter string   `json:"logfileWriter"`
+	LogfileFlags  []string `json:"logfileFlags"`
+	Formatter     string   `json:"formatter,omitempty"`
+}
+
+// levelColorsJSON is the JSON form of the screen colors of an output level
+type levelColorsJSON struct {
+	Prefix   string `json:"prefix"`
+	Metadata string `json:"metadata"`
+}
+
+// stateJSON is the JSON form of a State
+type stateJSON struct {
+	ScreenThreshold     string                     `json:"screenThreshold"`
+	LogfileThreshold    string                     `json:"logfileThreshold"`
+	LogFileName         string                     `json:"logFileName,omitempty"`
+	Quiet               bool                       `json:"quiet"`
+	Silent              bool                       `json:"silent"`
+	ColorConfig         string                     `json:"colorConfig"`
+	Colors              map[string]levelColorsJSON `json:"colors"`
+	WrapWidth           int                        `json:"wrapWidth"`
+	Wrap                []string                   `json:"wrap"`
+	StatusInterval      string                     `json:"statusInterval"`
+	GroupBufferSize     int                        `json:"groupBufferSize"`
+	StackTraceConfig    string                     `json:"stackTraceConfig"`
+	ScreenFormat        string                     `json:"screenFormat"`
+	LogfileFormat       string                     `json:"logfileFormat"`
+	DebugScope          string                     `json:"debugScope,omitempty"`
+	Levels              map[string]levelStateJSON  `json:"levels"`
+	DeferFunc           bool                       `json:"deferFunc"`
+	ExitFunc            bool                       `json:"exitFunc"`
+	Clock               bool                       `json:"clock"`
+	ScreenNewline       bool                       `json:"screenNewline"`
+	LogfileNewline      bool                       `json:"logfileNewline"`
+	CallDepth           int32                      `json:"callDepth"`
+	ErrorExitVal        int32                      `json:"errorExitVal"`
+	DefaultErrCode      int32                      `json:"defaultErrCode"`
+	ShortFileNameLength int32                      `json:"shortFileNameLength"`
+	LongFileNameLength  int32                      `json:"longFileNameLength"`
+	ShortFuncNameLength int32                      `json:"shortFuncNameLength"`
+	LongFuncNameLength  int32                      `json:"longFuncNameLength"`
+}
+
+// MarshalJSON dumps the State as JSON, writers and formatters are described
+// by name or type (eg: "stdout" or "file:/tmp/x.log") and flags and the stack
+// trace config use the names the PKG_OUT_*_FLAGS and PKG_OUT_STACK_TRACE_CONFIG
+// env settings use, the defer/exit/clock entries indicate if a non-default
+// func has been set
+func (s *State) MarshalJSON() ([]byte, error) {
+	j := stateJSON{
+		ScreenThreshold:     s.screenThreshold.String(),
+		LogfileThreshold:    s.logThreshold.String(),
+		LogFileName:         s.logFileName,
+		Quiet:               s.quiet,
+		Silent:              s.silent,
+		ColorConfig:         colorConfigString(s.colorConfig),
+		Colors:              make(map[string]levelColorsJSON),
+		WrapWidth:           s.wrapWidth,
+		Wrap:                []string{},
+		StatusInterval:      s.statusInterval.String(),
+		GroupBufferSize:     s.groupBufferSize,
+		StackTraceConfig:    stackTraceConfigString(s.stackTraceConfig),
+		ScreenFormat:        formatName(s.screenFormat),
+		LogfileFormat:       formatName(s.logfileFormat),
+		DebugScope:          s.debugScope,
+		Levels:              make(map[string]levelStateJSON),
+		DeferFunc:           s.deferFunc != nil,
+		ExitFunc:            s.exitFunc != nil,
+		Clock:               s.clock != nil,
+		ScreenNewline:       s.screenNewline,
+		LogfileNewline:      s.logfileNewline,
+		CallDepth:           s.callDepth,
+		ErrorExitVal:        s.errorExitVal,
+		DefaultErrCode:      s.defaultErrCode,
+		ShortFileNameLength: s.shortFileNameLength,
+		LongFileNameLength:  s.longFileNameLength,
+		ShortFuncNameLength: s.shortFuncNameLength,
+		LongFuncNameLength:  s.longFuncNameLength,
+	}
+	for _, l := range s.levels {
+		lj := levelStateJSON{
+			Prefix:        l.prefix,
+			ScreenWriter:  writerName(l.screenHndl),
+			ScreenFlags:   flagNames(l.screenFlags),
+			LogfileWriter: writerName(l.logfileHndl),
+			LogfileFlags:  flagNames(l.logFlags),
+		}
+		if l.formatter != nil {
+			lj.Formatter = fmt.Sprintf("%T", l.formatter)
+		}
+		j.Levels[l.level.String()] = lj
+		j.Colors[l.level.String()] = levelColorsJSON{Prefix: l.prefixColor, Metadata: l.metaColor}
+		if l.wrap {
+			j.Wrap = append(j.Wrap, l.level.String())
+		}
+	}
+	return json.Marshal(j)
+}
+
+// formatName returns the name of an output format ("" is the text format)
+func formatName(format string) string {
+	if format == "" {
+		return FormatText
+	}
+	return format
+}
+
+// colorConfigString returns the name of a color config (see SetColorConfig())
+func colorConfigString(cfg int) string {
+	switch cfg {
+	case ColorAlways:
+		return "always"
+	case ColorNever:
+		return "never"
+	}
+	return "auto"
+}
+
+// writerName returns a short description of an output writer for debugging
+func writerName(w io.Writer) string {
+	switch w {
+	case nil:
+		return ""
+	case os.Stdout:
+		return "stdout"
+	case os.Stderr:
+		return "stderr"
+	case ioutil.Discard:
+		return "discard"
+	}
+	switch wr := w.(type) {
+	case *os.File:
+		return "file:" + wr.Name()
+	case *RotateWriter:
+		return "rotate:" + wr.filename
+	}
+	return fmt.Sprintf("%T", w)
+}
+
+// flagNames returns the names of the given output flags (Ldate, Ltime, ..),
+// these are the names used in the PKG_OUT_SCREEN_FLAGS env setting and such
+func flagNames(flags int) []string {
+	names := []string{}
+	for _, f := range []struct {
+		flag int
+		name string
+	}{
+		{Lpid, "pid"},
+		{Llevel, "level"},
+		{Ldate, "date"},
+		{Ltime, "time"},
+		{Lmicroseconds, "micro"},
+		{Lshortfile, "shortfile"},
+		{Llongfile, "longfile"},
+		{Lshortfunc, "shortfunc"},
+		{Llongfunc, "longfunc"},
+	} {
+		if flags&f.flag != 0 {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
+
+// stackTraceConfigString returns the stack trace config in the same form
+// as the PKG_OUT_STACK_TRACE_CONFIG env setting, eg: "logfile,nonzeroerrorexit"
+func stackTraceConfigString(cfg int) string {
+	var parts []string
+	switch cfg & ForBoth {
+	case ForBoth:
+		parts = append(parts, "both")
+	case ForScreen:
+		parts = append(parts, "screen")
+	case ForLogfile:
+		parts = append(parts, "logfile")
+	}
+	if cfg&StackTraceNonZeroErrorExit != 0 {
+		parts = append(parts, "nonzeroerrorexit")
+	}
+	if cfg&StackTraceErrorExit != 0 {
+		parts = append(parts, "errorexit")
+	}
+	if cfg&StackTraceAllIssues != 0 {
+		parts = append(parts, "allissues")
+	}
+	if parts == nil {
+		return "off"
+	}
+	return strings.Join(parts, ",")
+}
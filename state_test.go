@@ -0,0 +1,77 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package test for: out/state.go
+//   Testing in this file focuses on taking a snapshot of the 'out' settings,
+//   restoring it and dumping it as JSON
+
+package out
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/dvln/testify/assert"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	snap := Snapshot()
+
+	screenBuf := new(bytes.Buffer)
+	SetWriter(LevelAll, screenBuf, ForScreen)
+	SetThreshold(LevelDebug, ForScreen)
+	SetThreshold(LevelNote, ForLogfile)
+	SetPrefix(LevelNote, "NOTE> ")
+	SetFlags(LevelNote, Lpid, ForScreen)
+	SetFormatter(LevelIssue, replaceMsg{})
+	SetStackTraceConfig(ForBoth | StackTraceAllIssues)
+	SetDeferFunc(func(exitVal int) {})
+	SetShortFuncNameLength(40)
+	SetErrorExitVal(9)
+	Note("no newline")
+
+	snap.Restore()
+
+	assert.Equal(t, os.Stdout, Writer(LevelNote, ForScreen))
+	assert.Equal(t, os.Stderr, Writer(LevelError, ForScreen))
+	assert.Equal(t, LevelInfo, Threshold(ForScreen))
+	assert.Equal(t, LevelDiscard, Threshold(ForLogfile))
+	assert.Equal(t, "Note: ", Prefix(LevelNote))
+	assert.Equal(t, 0, Flags(LevelNote, ForScreen))
+	assert.Equal(t, nil, LevelFormatter(LevelIssue))
+	assert.Equal(t, StackTraceExitToLogfile, StackTraceConfig())
+	assert.Equal(t, true, DeferFunc() == nil)
+	assert.Equal(t, int32(14), ShortFuncNameLength())
+	assert.Equal(t, int32(-1), ErrorExitVal())
+	assert.Equal(t, true, screenNewline)
+
+	ResetOutPkg()
+}
+
+func TestSnapshotJSON(t *testing.T) {
+	SetThreshold(LevelVerbose, ForScreen)
+	SetFormatter(LevelIssue, replaceMsg{})
+	snapJSON, err := json.Marshal(Snapshot())
+	ResetOutPkg()
+
+	assert.Equal(t, nil, err)
+	var dump map[string]interface{}
+	assert.Equal(t, nil, json.Unmarshal(snapJSON, &dump))
+	assert.Equal(t, "VERBOSE", dump["screenThreshold"])
+	assert.Equal(t, "DISCARD", dump["logfileThreshold"])
+	assert.Equal(t, "logfile,nonzeroerrorexit", dump["stackTraceConfig"])
+	assert.Contains(t, string(snapJSON), `"ERROR":{"prefix":"Error: ","screenWriter":"stderr","screenFlags":[],"logfileWriter":"discard","logfileFlags":["pid","level","date","time","micro","shortfile","shortfunc"]}`)
+	assert.Contains(t, string(snapJSON), `"formatter":"out.replaceMsg"`)
+}
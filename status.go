@@ -0,0 +1,328 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package out
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// statusRedrawInterval is the most often a status line on a terminal is
+// redrawn as its progress is updated (label changes are drawn right away)
+const statusRedrawInterval = 100 * time.Millisecond
+
+// statusBarWidth is the width of the bar of a progress status line
+const statusBarWidth = 20
+
+var (
+	// statuses are the active status lines, in the order they were created,
+	// these (and the Status fields) are protected by the 'out' mutex
+	statuses []*Status
+
+	// statusDrawn is the number of status lines currently on the terminal
+	statusDrawn int
+
+	// statusInterval is how often a plain status line is printed when the
+	// screen isn't a terminal, see SetStatusInterval()
+	statusInterval = 10 * time.Second
+
+	// statusIsTerminal decides if status lines are drawn and redrawn at the
+	// bottom of the screen or printed as plain lines (replaceable for tests)
+	statusIsTerminal = isTerminal
+)
+
+// Status is a line at the bottom of the screen showing what a tool is busy
+// with, optionally with progress (a bar, the rate and the ETA), see
+// NewStatus() and NewProgress().  Status lines stay below any other screen
+// output, they're erased and redrawn around every screen write, and are
+// never written to the log file.
+type Status struct {
+	label    string
+	progress bool
+	bytes    bool
+	total    int64
+	current  int64
+	start    time.Time
+	lastShow time.Time
+}
+
+// NewStatus adds a status line with the given label (formatted as with
+// fmt.Sprintf()), eg:
+//
+//	status := out.NewStatus("Scanning %s", dir)
+//	for _, file := range files {
+//		status.Set("Scanning %s", file)
+//		...
+//	}
+//	status.Done()
+//
+// On a terminal the status lines are kept at the bottom of the screen below
+// the regular output (which may come from any goroutine), otherwise a plain
+// line with the status is printed now and then (see SetStatusInterval()).
+// Status lines go to the Info level screen writer and are only shown if
+// Info output is (ie: not in quiet or silent mode or with a higher screen
+// threshold).  Call Done() to remove the status line.
+func NewStatus(format string, v ...interface{}) *Status {
+	return addStatus(&Status{label: fmt.Sprintf(format, v...)})
+}
+
+// NewProgress adds a status line that tracks progress towards the given
+// total (0 or less if unknown) and shows a progress bar with the rate and
+// ETA, see NewStatus().  Update it via Add() or SetCurrent(), or use it as
+// an io.Writer that counts the bytes written, eg:
+//
+//	bar := out.NewProgress(resp.ContentLength, "Downloading %s", name).Bytes()
+//	_, err := io.Copy(file, io.TeeReader(resp.Body, bar))
+//	bar.Done()
+func NewProgress(total int64, format string, v ...interface{}) *Status {
+	return addStatus(&Status{label: fmt.Sprintf(format, v...), progress: true, total: total})
+}
+
+// addStatus adds the status line and shows it
+func addStatus(s *Status) *Status {
+	mutex.Lock()
+	{
+		s.start = now()
+		statuses = append(statuses, s)
+		s.showLocked(true)
+	}
+	mutex.Unlock()
+	return s
+}
+
+// Bytes indicates the progress is counted in bytes so the amounts and rate
+// are shown as such (eg: "4.5 MB/s"), it returns the Status for chaining
+func (s *Status) Bytes() *Status {
+	mutex.Lock()
+	{
+		s.bytes = true
+	}
+	mutex.Unlock()
+	return s
+}
+
+// Set changes the label of the status line
+func (s *Status) Set(format string, v ...interface{}) {
+	label := fmt.Sprintf(format, v...)
+	mutex.Lock()
+	{
+		s.label = label
+		s.showLocked(true)
+	}
+	mutex.Unlock()
+}
+
+// Add adds to the progress made
+func (s *Status) Add(n int64) {
+	mutex.Lock()
+	{
+		s.current += n
+		s.showLocked(false)
+	}
+	mutex.Unlock()
+}
+
+// SetCurrent sets the progress made
+func (s *Status) SetCurrent(n int64) {
+	mutex.Lock()
+	{
+		s.current = n
+		s.showLocked(false)
+	}
+	mutex.Unlock()
+}
+
+// Write satisfies the io.Writer interface by adding the number of bytes
+// written to the progress made, the data itself isn't used
+func (s *Status) Write(p []byte) (int, error) {
+	s.Add(int64(len(p)))
+	return len(p), nil
+}
+
+// Done removes the status line
+func (s *Status) Done() {
+	mutex.Lock()
+	{
+		for i, active := range statuses {
+			if active == s {
+				statuses = append(statuses[:i], statuses[i+1:]...)
+				break
+			}
+		}
+		if statusDrawn > 0 {
+			eraseStatusLocked()
+			drawStatusLocked()
+		}
+	}
+	mutex.Unlock()
+}
+
+// StatusInterval returns how often a plain status line is printed when the
+// screen isn't a terminal, see SetStatusInterval()
+func StatusInterval() time.Duration {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return statusInterval
+}
+
+// SetStatusInterval sets how often (at most) a status line is printed as a
+// plain line when the screen isn't a terminal (eg: in a CI log), the default
+// is every 10 seconds
+func SetStatusInterval(interval time.Duration) {
+	mutex.Lock()
+	{
+		statusInterval = interval
+	}
+	mutex.Unlock()
+}
+
+// statusWriterLocked returns the writer that status lines go to (the Info
+// screen writer) and if they're to be shown at all, the mutex must be held
+func statusWriterLocked() (io.Writer, bool) {
+	INFO.mu.RLock()
+	w := INFO.screenHndl
+	INFO.mu.RUnlock()
+	return w, screenWanted(LevelInfo, screenThreshold, quiet, silent, false)
+}
+
+// showLocked shows the updated status, on a terminal the status lines are
+// redrawn (at most every statusRedrawInterval unless forced) and otherwise
+// a plain line is printed (at most every statusInterval), the mutex must be
+// held.  Nothing is shown while the screen output is mid-line.
+func (s *Status) showLocked(force bool) {
+	w, shown := statusWriterLocked()
+	if !shown || !screenNewline {
+		return
+	}
+	t := now()
+	if !statusIsTerminal(w) {
+		if !s.lastShow.IsZero() && t.Sub(s.lastShow) < statusInterval {
+			return
+		}
+		s.lastShow = t
+		w.Write([]byte(s.render(t, false) + "\n"))
+		return
+	}
+	if !force && t.Sub(s.lastShow) < statusRedrawInterval {
+		return
+	}
+	s.lastShow = t
+	eraseStatusLocked()
+	drawStatusLocked()
+}
+
+// eraseStatusLocked removes any status lines from the terminal, the cursor
+// ends up where they started, the mutex must be held
+func eraseStatusLocked() {
+	if statusDrawn == 0 {
+		return
+	}
+	w, _ := statusWriterLocked()
+	// move up to the 1st status line and clear to the end of the screen
+	fmt.Fprintf(w, "\x1b[%dA\r\x1b[J", statusDrawn)
+	statusDrawn = 0
+}
+
+// drawStatusLocked draws the status lines at the cursor (if on a terminal),
+// each is cut to the terminal width so the lines can be erased again, the
+// mutex must be held
+func drawStatusLocked() {
+	if len(statuses) == 0 {
+		return
+	}
+	w, shown := statusWriterLocked()
+	if !shown || !statusIsTerminal(w) {
+		return
+	}
+	width := terminalWidth(w)
+	t := now()
+	var b strings.Builder
+	for _, s := range statuses {
+		line := s.render(t, true)
+		if width > 1 && displayWidth(line) > width-1 {
+			line, _ = splitAtWidth(line, width-1)
+		}
+		b.WriteString(line + "\x1b[0m\n")
+	}
+	w.Write([]byte(b.String()))
+	statusDrawn = len(statuses)
+}
+
+// screenWriteLocked writes screen output with any status lines erased first
+// and drawn again below the output once it ends a line, the mutex must be
+// held
+func screenWriteLocked(w io.Writer, b []byte) (int, error) {
+	if len(statuses) == 0 {
+		return w.Write(b)
+	}
+	eraseStatusLocked()
+	n, err := w.Write(b)
+	if len(b) != 0 && b[len(b)-1] == '\n' {
+		drawStatusLocked()
+	}
+	return n, err
+}
+
+// render returns the status line, ie: the label and, if tracking progress,
+// the bar (if wanted), percentage, rate and ETA
+func (s *Status) render(t time.Time, bar bool) string {
+	if !s.progress {
+		return s.label
+	}
+	parts := []string{s.label}
+	if s.total > 0 {
+		done := math.Min(float64(s.current)/float64(s.total), 1)
+		if bar {
+			filled := int(done * statusBarWidth)
+			parts = append(parts, "["+strings.Repeat("=", filled)+strings.Repeat(" ", statusBarWidth-filled)+"]")
+		}
+		parts = append(parts, fmt.Sprintf("%3d%%", int(done*100)))
+	} else {
+		parts = append(parts, s.amount(float64(s.current)))
+	}
+	elapsed := t.Sub(s.start).Seconds()
+	if elapsed > 0 && s.current > 0 {
+		rate := float64(s.current) / elapsed
+		parts = append(parts, s.amount(rate)+"/s")
+		if s.total > s.current {
+			eta := time.Duration(float64(s.total-s.current) / rate * float64(time.Second))
+			parts = append(parts, "ETA "+eta.Round(time.Second).String())
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// amount formats an amount of progress (or rate), in bytes if so counted
+func (s *Status) amount(v float64) string {
+	if !s.bytes {
+		if v == math.Trunc(v) {
+			return strconv.FormatInt(int64(v), 10)
+		}
+		return strconv.FormatFloat(v, 'f', 1, 64)
+	}
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	unit := 0
+	for v >= 1024 && unit < len(units)-1 {
+		v /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%d B", int64(v))
+	}
+	return fmt.Sprintf("%.1f %s", v, units[unit])
+}
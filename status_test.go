@@ -0,0 +1,103 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package test for: out/status.go
+//   Testing in this file focuses on the status and progress lines, redrawn
+//   around other screen output on a terminal or printed as plain lines
+
+package out
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/dvln/testify/assert"
+)
+
+// fakeClock is a settable clock for SetClock()
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func TestStatusPlain(t *testing.T) {
+	screenBuf := new(bytes.Buffer)
+	logBuf := new(bytes.Buffer)
+	SetWriter(LevelAll, screenBuf, ForScreen)
+	SetWriter(LevelAll, logBuf, ForLogfile)
+	SetThreshold(LevelInfo, ForLogfile)
+	SetFlags(LevelAll, 0, ForLogfile)
+	clk := &fakeClock{t: time.Date(2016, 3, 1, 12, 0, 0, 0, time.UTC)}
+	SetClock(clk.now)
+
+	bar := NewProgress(100, "Downloading x")
+	bar.Add(50)
+	Println("other output")
+	clk.t = clk.t.Add(10 * time.Second)
+	bar.Add(0)
+	clk.t = clk.t.Add(5 * time.Second)
+	bar.Add(25)
+	bar.Done()
+	assert.Equal(t, "Downloading x   0%\nother output\nDownloading x  50% 5/s ETA 10s\n", screenBuf.String())
+	assert.Equal(t, "other output\n", logBuf.String())
+
+	// status lines follow Info output, so none in quiet mode
+	screenBuf.Reset()
+	SetQuiet(true)
+	NewStatus("Scanning").Done()
+	assert.Equal(t, "", screenBuf.String())
+	ResetOutPkg()
+}
+
+func TestStatusTerminal(t *testing.T) {
+	statusIsTerminal = func(io.Writer) bool { return true }
+	defer func() { statusIsTerminal = isTerminal }()
+	screenBuf := new(bytes.Buffer)
+	SetWriter(LevelAll, screenBuf, ForScreen)
+	erase := "\x1b[1A\r\x1b[J"
+
+	status := NewStatus("Working")
+	assert.Equal(t, "Working\x1b[0m\n", screenBuf.String())
+	screenBuf.Reset()
+	Println("line")
+	assert.Equal(t, erase+"line\nWorking\x1b[0m\n", screenBuf.String())
+
+	// mid-line output isn't broken up by the status line
+	screenBuf.Reset()
+	Print("partial")
+	status.Set("Still working")
+	Println(" line")
+	assert.Equal(t, erase+"partial line\nStill working\x1b[0m\n", screenBuf.String())
+
+	screenBuf.Reset()
+	status.Done()
+	assert.Equal(t, erase, screenBuf.String())
+	screenBuf.Reset()
+	Println("after")
+	assert.Equal(t, "after\n", screenBuf.String())
+	ResetOutPkg()
+}
+
+func TestStatusRender(t *testing.T) {
+	start := time.Date(2016, 3, 1, 12, 0, 0, 0, time.UTC)
+	s := &Status{label: "Copying", progress: true, bytes: true, total: 4 << 20, current: 1 << 20, start: start}
+	assert.Equal(t, "Copying [=====               ]  25% 512.0 KB/s ETA 6s", s.render(start.Add(2*time.Second), true))
+	assert.Equal(t, "Copying  25% 512.0 KB/s ETA 6s", s.render(start.Add(2*time.Second), false))
+	s = &Status{label: "Items", progress: true, current: 3, start: start}
+	assert.Equal(t, "Items 3 1.5/s", s.render(start.Add(2*time.Second), true))
+	s = &Status{label: "Just a label"}
+	assert.Equal(t, "Just a label", s.render(start, true))
+}
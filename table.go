@@ -0,0 +1,204 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package out
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// tableRowMsg is the message of the structured records of table rows
+const tableRowMsg = "table row"
+
+// tableColumnGap is the space between the columns of a table
+const tableColumnGap = "  "
+
+// tableMinColumnWidth is the narrowest a column is truncated to
+const tableMinColumnWidth = 4
+
+// Table outputs the rows as a table at the given level, the columns are
+// as wide as their widest cell (wide Unicode runes and colors included) and
+// the headers (if any) are underlined, eg:
+//
+//	out.Table(out.LevelInfo, []string{"NAME", "SIZE"}, [][]string{
+//		{"main.go", "1.2 KB"},
+//		{"README.md", "800 B"},
+//	})
+//
+//	NAME       SIZE
+//	----       ----
+//	main.go    1.2 KB
+//	README.md  800 B
+//
+// Every line of the table gets the level prefix and flag metadata so the
+// table stays aligned (eg: "Note: " in front of each line), cells can have
+// multiple lines.  If the table is too wide for the screen (see
+// SetWrapWidth()) the widest columns are truncated on the screen, the log
+// file gets the full table.  If a target has a structured output format
+// (see SetOutputFormat()) it gets one record per row instead, with the
+// headers as the field names.
+func Table(level Level, headers []string, rows [][]string) {
+	o := levelOutputter(level)
+	if o == nil || (len(headers) == 0 && len(rows) == 0) {
+		return
+	}
+	o.tableOutput(headers, rows)
+}
+
+// tableOutput renders the table and dumps it, see Table(), it's a separate
+// method so the call depth matches the other output funcs
+func (o *LvlOutput) tableOutput(headers []string, rows [][]string) {
+	widths := tableWidths(headers, rows)
+	full := renderTable(headers, rows, widths)
+	o.mu.RLock()
+	prefix := o.prefix
+	w := o.screenHndl
+	o.mu.RUnlock()
+	screen := full
+	if width := screenWidth(w); width > 0 {
+		// the room left after the prefix and any flag metadata on the screen,
+		// the metadata is gathered here rather than via stringOutput() and
+		// doPrefixing(), ie: two call levels less deep
+		leader, _, _ := o.insertFlagMetadata("x", ForScreen, AlwaysInsert, nil, false, outputOpts{}, int(CallDepth())-2)
+		room := width - displayWidth(prefix) - (displayWidth(leader) - 1)
+		if fitTable(widths, room) {
+			screen = renderTable(headers, rows, widths)
+		}
+	}
+	var records [][]ErrField
+	for _, row := range rows {
+		var record []ErrField
+		for col, cell := range row {
+			key := fmt.Sprintf("col%d", col+1)
+			if col < len(headers) && headers[col] != "" {
+				key = strings.Join(strings.Fields(headers[col]), "_")
+			}
+			record = append(record, ErrField{key, cell})
+		}
+		records = append(records, record)
+	}
+	opts := outputOpts{logfileMsg: full, records: records}
+	if _, err := o.stringOutput(screen, false, 0, opts); err != nil {
+		mutex.Lock()
+		{
+			fmt.Fprintf(os.Stderr, "%s", err)
+		}
+		mutex.Unlock()
+	}
+}
+
+// tableWidths returns the width of each column, ie: of its widest cell
+func tableWidths(headers []string, rows [][]string) []int {
+	var widths []int
+	for _, row := range append([][]string{headers}, rows...) {
+		for col, cell := range row {
+			if col == len(widths) {
+				widths = append(widths, 0)
+			}
+			for _, line := range strings.Split(cell, "\n") {
+				if width := displayWidth(line); width > widths[col] {
+					widths[col] = width
+				}
+			}
+		}
+	}
+	return widths
+}
+
+// fitTable narrows the widest columns until the table fits the given room
+// (columns aren't narrowed below tableMinColumnWidth), it returns true if
+// any column was narrowed
+func fitTable(widths []int, room int) bool {
+	total := len(tableColumnGap) * (len(widths) - 1)
+	for _, width := range widths {
+		total += width
+	}
+	narrowed := false
+	for total > room {
+		widest := 0
+		for col, width := range widths {
+			if width > widths[widest] {
+				widest = col
+			}
+		}
+		if widths[widest] <= tableMinColumnWidth {
+			break
+		}
+		widths[widest]--
+		total--
+		narrowed = true
+	}
+	return narrowed
+}
+
+// renderTable lays out the table with the given column widths, cells that
+// don't fit are truncated with a "…", a row with multi-line cells takes up
+// as many lines as its tallest cell
+func renderTable(headers []string, rows [][]string, widths []int) string {
+	var lines []string
+	addRow := func(row []string) {
+		cells := make([][]string, len(row))
+		height := 1
+		for col, cell := range row {
+			cells[col] = strings.Split(cell, "\n")
+			if len(cells[col]) > height {
+				height = len(cells[col])
+			}
+		}
+		for i := 0; i < height; i++ {
+			var line strings.Builder
+			for col := range row {
+				text := ""
+				if i < len(cells[col]) {
+					text = truncateWidth(cells[col][i], widths[col])
+				}
+				line.WriteString(text)
+				if col < len(row)-1 {
+					line.WriteString(strings.Repeat(" ", widths[col]-displayWidth(text)) + tableColumnGap)
+				}
+			}
+			lines = append(lines, strings.TrimRight(line.String(), " "))
+		}
+	}
+	if len(headers) != 0 {
+		addRow(headers)
+		underline := make([]string, len(headers))
+		for col, header := range headers {
+			underline[col] = strings.Repeat("-", displayWidth(truncateWidth(header, widths[col])))
+		}
+		addRow(underline)
+	}
+	for _, row := range rows {
+		addRow(row)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// truncateWidth cuts the string to the given display width, ending it with
+// a "…" if it was cut (any colors are reset after the cut)
+func truncateWidth(s string, width int) string {
+	if displayWidth(s) <= width {
+		return s
+	}
+	head, _ := splitAtWidth(s, width-1)
+	if displayWidth(head) > width-1 {
+		head = "" // a wide rune that doesn't fit at all
+	}
+	head = strings.TrimRight(head, " ")
+	if strings.Contains(head, "\x1b[") {
+		head += "\x1b[0m"
+	}
+	return head + "…"
+}
@@ -0,0 +1,76 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package test for: out/table.go
+//   Testing in this file focuses on table layout, fitting tables to the
+//   screen width and dumping table rows as structured records
+
+package out
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dvln/testify/assert"
+)
+
+func TestTable(t *testing.T) {
+	screenBuf := new(bytes.Buffer)
+	logBuf := new(bytes.Buffer)
+	SetWriter(LevelAll, screenBuf, ForScreen)
+	SetWriter(LevelAll, logBuf, ForLogfile)
+	SetThreshold(LevelInfo, ForLogfile)
+	SetFlags(LevelAll, 0, ForLogfile)
+	headers := []string{"NAME", "SIZE", "NOTES"}
+	rows := [][]string{
+		{"main.go", "1.2 KB", "entry point"},
+		{"日本.txt", "800 B", "two\nlines"},
+	}
+
+	Table(LevelNote, headers, rows)
+	table := "Note: NAME      SIZE    NOTES\n" +
+		"Note: ----      ----    -----\n" +
+		"Note: main.go   1.2 KB  entry point\n" +
+		"Note: 日本.txt  800 B   two\n" +
+		"Note:                   lines\n"
+	assert.Equal(t, table, screenBuf.String())
+	assert.Equal(t, table, logBuf.String())
+
+	// the screen gets a table that fits, the log file the full table
+	screenBuf.Reset()
+	logBuf.Reset()
+	SetWrapWidth(30)
+	Table(LevelNote, headers, rows)
+	assert.Equal(t, "Note: NAME     SIZE    NOTES\n"+
+		"Note: ----     ----    -----\n"+
+		"Note: main.go  1.2 KB  entry…\n"+
+		"Note: 日本.t…  800 B   two\n"+
+		"Note:                  lines\n", screenBuf.String())
+	assert.Equal(t, table, logBuf.String())
+
+	// structured formats get a record per row
+	logBuf.Reset()
+	SetOutputFormat(FormatLogfmt, ForLogfile)
+	Table(LevelNote, headers, rows[:1])
+	assert.Contains(t, logBuf.String(), `msg="table row"`)
+	assert.Contains(t, logBuf.String(), `file=table_test.go`)
+	assert.Contains(t, logBuf.String(), `NAME=main.go SIZE="1.2 KB" NOTES="entry point"`)
+	assert.Equal(t, 1, bytes.Count(logBuf.Bytes(), []byte("\n")))
+
+	// nothing to show, nothing shown
+	screenBuf.Reset()
+	Table(LevelNote, nil, nil)
+	Table(LevelDiscard, headers, rows)
+	assert.Equal(t, "", screenBuf.String())
+	ResetOutPkg()
+}
@@ -0,0 +1,169 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package out
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// TestingT is the subset of the Go testing.TB interface used by ToTesting()
+// so that the 'out' package need not import the 'testing' package
+type TestingT interface {
+	Helper()
+	Log(args ...interface{})
+	Error(args ...interface{})
+	Cleanup(func())
+}
+
+// testOutput is implemented by tests that can be written to without the
+// file and line # that t.Log() adds (eg: a testing.T as of Go 1.25)
+type testOutput interface {
+	Output() io.Writer
+	Fail()
+}
+
+// outPkgPath is used to skip over 'out' pkg frames when finding the caller
+var outPkgPath = reflect.TypeOf(LvlOutput{}).PkgPath()
+
+// testWriter is the screen io.Writer used for each level by ToTesting(), it
+// buffers partial lines and hands complete output to t.Log() (or t.Error()
+// if the level is one that should fail the test) prefixed by the file and
+// line # of the caller of the 'out' pkg
+type testWriter struct {
+	mu     sync.Mutex
+	t      TestingT
+	fail   bool
+	buf    []byte
+	caller string
+}
+
+// Write buffers the given output and logs it to the test once a newline
+// has been written, it never fails
+func (w *testWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) == 0 {
+		w.caller = callerOutsideOut()
+	}
+	w.buf = append(w.buf, p...)
+	if len(w.buf) != 0 && w.buf[len(w.buf)-1] == '\n' {
+		w.flush()
+	}
+	return len(p), nil
+}
+
+// flush logs any buffered output to the test, caller must hold the lock, the
+// caller of the 'out' pkg is the only location given if the test supports
+// output without one (see testOutput), otherwise t.Log() adds its own (an
+// 'out' pkg location as t.Helper() can't reach the 'out' pkg frames)
+func (w *testWriter) flush() {
+	if len(w.buf) == 0 {
+		return
+	}
+	msg := strings.TrimSuffix(string(w.buf), "\n")
+	if w.caller != "" {
+		msg = w.caller + ": " + msg
+	}
+	w.buf = w.buf[:0]
+	if tOut, ok := w.t.(testOutput); ok {
+		io.WriteString(tOut.Output(), msg+"\n")
+		if w.fail {
+			tOut.Fail()
+		}
+		return
+	}
+	if w.fail {
+		w.t.Error(msg)
+	} else {
+		w.t.Log(msg)
+	}
+}
+
+// callerOutsideOut returns the "file:line#" of the first caller on the stack
+// that is not in the 'out' package (ie: where 'out' was called from), the
+// 'out' pkg test files count as callers
+func callerOutsideOut() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, outPkgPath+".") || strings.HasSuffix(frame.File, "_test.go") {
+			return fmt.Sprintf("%s:%d", filepath.Base(frame.File), frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// ToTesting routes the screen output of the 'out' package to the given test
+// (a testing.T or testing.B) via t.Log() so that it is attributed to the test
+// and to where 'out' was called from (the caller file/line is added to each
+// bit of output), use it at the start of a test:
+//
+//	out.ToTesting(t, out.LevelDebug, out.LevelError)
+//
+// The optional 1st level is the screen threshold to use while routing to the
+// test (default: LevelTrace, ie: all output) and the optional 2nd level is
+// the level at or above which output fails the test via t.Error() (default:
+// LevelDiscard, ie: never fail).  The previous screen writers and threshold
+// are restored via t.Cleanup() once the test is done.  Note that this does
+// not change the exit behavior of Fatal() and friends (see SetExitFunc()).
+func ToTesting(t TestingT, levels ...Level) {
+	t.Helper()
+	threshold := LevelTrace
+	failLevel := LevelDiscard
+	if len(levels) > 0 {
+		threshold = levels[0]
+	}
+	if len(levels) > 1 {
+		failLevel = levels[1]
+	}
+	prevThreshold := Threshold(ForScreen)
+	prevWriters := make([]io.Writer, len(outputters))
+	writers := make([]*testWriter, len(outputters))
+	resetTerminals()
+	for i, o := range outputters {
+		o.mu.Lock()
+		prevWriters[i] = o.screenHndl
+		writers[i] = &testWriter{t: t, fail: o.level >= failLevel}
+		o.screenHndl = writers[i]
+		o.mu.Unlock()
+	}
+	SetThreshold(threshold, ForScreen)
+	ResetNewline(true, ForScreen)
+
+	t.Cleanup(func() {
+		resetTerminals()
+		for i, o := range outputters {
+			o.mu.Lock()
+			if o.screenHndl == io.Writer(writers[i]) {
+				o.screenHndl = prevWriters[i]
+			}
+			o.mu.Unlock()
+			writers[i].mu.Lock()
+			writers[i].flush()
+			writers[i].mu.Unlock()
+		}
+		SetThreshold(prevThreshold, ForScreen)
+		ResetNewline(true, ForScreen)
+	})
+}
@@ -0,0 +1,99 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package test for: out/testwriter.go
+//   Testing in this file focuses on routing 'out' output to a test via the
+//   ToTesting() routine
+
+package out
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/dvln/testify/assert"
+)
+
+// fakeT records what is logged to it and the cleanup funcs registered
+type fakeT struct {
+	logs     []string
+	errs     []string
+	cleanups []func()
+}
+
+func (f *fakeT) Helper()                   {}
+func (f *fakeT) Log(args ...interface{})   { f.logs = append(f.logs, fmt.Sprint(args...)) }
+func (f *fakeT) Error(args ...interface{}) { f.errs = append(f.errs, fmt.Sprint(args...)) }
+func (f *fakeT) Cleanup(c func())          { f.cleanups = append(f.cleanups, c) }
+
+// fakeOutputT is a fakeT that can be written to without a location
+type fakeOutputT struct {
+	fakeT
+	out    bytes.Buffer
+	failed bool
+}
+
+func (f *fakeOutputT) Output() io.Writer { return &f.out }
+func (f *fakeOutputT) Fail()             { f.failed = true }
+
+func TestToTesting(t *testing.T) {
+	ft := &fakeT{}
+	SetFlags(LevelDebug, 0, ForScreen)
+	ToTesting(ft, LevelDebug, LevelError)
+
+	_, _, line, _ := runtime.Caller(0)
+	Debugf("value %d\n", 42)
+	Issue("partial ")
+	Issue("line\n")
+	Error("bad thing\n")
+	Trace("not shown\n")
+	Note("unfinished")
+
+	assert.Equal(t, 1, len(ft.cleanups))
+	ft.cleanups[0]()
+
+	assert.Equal(t, []string{
+		fmt.Sprintf("testwriter_test.go:%d: Debug: value 42", line+1),
+		fmt.Sprintf("testwriter_test.go:%d: Issue: partial line", line+2),
+		fmt.Sprintf("testwriter_test.go:%d: Note: unfinished", line+6),
+	}, ft.logs)
+	assert.Equal(t, []string{fmt.Sprintf("testwriter_test.go:%d: Error: bad thing", line+4)}, ft.errs)
+	assert.Equal(t, os.Stdout, Writer(LevelDebug, ForScreen))
+	assert.Equal(t, os.Stderr, Writer(LevelError, ForScreen))
+	assert.Equal(t, LevelInfo, Threshold(ForScreen))
+
+	// tests that can be written to directly only get the caller's location
+	ot := &fakeOutputT{}
+	ToTesting(ot, LevelDebug, LevelError)
+	_, _, line, _ = runtime.Caller(0)
+	Debugf("value %d\n", 42)
+	Error("bad thing\n")
+	ot.cleanups[0]()
+	assert.Equal(t, fmt.Sprintf("testwriter_test.go:%d: Debug: value 42\n", line+1)+
+		fmt.Sprintf("testwriter_test.go:%d: Error: bad thing\n", line+2), ot.out.String())
+	assert.Equal(t, true, ot.failed)
+	assert.Equal(t, 0, len(ot.logs)+len(ot.errs))
+
+	// and with a real test, nothing should fail it here:
+	t.Run("real", func(t *testing.T) {
+		ToTesting(t)
+		Debugln("routed to the test log")
+	})
+	assert.Equal(t, os.Stdout, Writer(LevelDebug, ForScreen))
+
+	ResetOutPkg()
+}
@@ -0,0 +1,173 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package out
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// timingMu protects the timing aggregates
+	timingMu sync.Mutex
+
+	// timings holds the aggregated durations of the timed spans by name
+	timings = make(map[string]*TimingStats)
+)
+
+// TimingStats are the aggregated durations of the timed spans with the same
+// name, see Timed() and Timings()
+type TimingStats struct {
+	Name     string        // the format string of the spans
+	Count    int           // the number of spans that ended
+	Failures int           // how many of those ended with an error
+	Total    time.Duration // the sum of their durations
+	Min      time.Duration // the shortest one
+	Max      time.Duration // the longest one
+}
+
+// Avg returns the average duration of the spans
+func (ts TimingStats) Avg() time.Duration {
+	if ts.Count == 0 {
+		return 0
+	}
+	return ts.Total / time.Duration(ts.Count)
+}
+
+// Timed starts a timed span, the span name (formatted as with fmt.Sprintf())
+// is output at the given level and the returned func ends the span, eg:
+//
+//	defer out.Timed(out.LevelVerbose, "sync repo %s", name)()
+//
+//	sync repo dvln started
+//	sync repo dvln done (1.52s)
+//
+// If an error is passed in when ending the span it's marked as failed (eg:
+// "sync repo dvln failed (1.52s): no such host"), nil errors are ignored:
+//
+//	done := out.Timed(out.LevelVerbose, "sync repo %s", name)
+//	err := sync(name)
+//	done(err)
+//
+// Ending a span again does nothing.  The duration (via the clock func, see
+// SetClock()) is also added to the aggregates of the spans with the same
+// format string (eg: all "sync repo %s" spans), see Timings() and
+// DumpTimings(), this is done even if the level isn't output anywhere.
+func Timed(level Level, format string, v ...interface{}) func(...error) {
+	o := levelOutputter(level)
+	name := fmt.Sprintf(format, v...)
+	if o != nil {
+		o.outputf(false, 0, outputOpts{}, "%s started\n", name)
+	}
+	start := now()
+	var ended int32
+	return func(errs ...error) {
+		if !atomic.CompareAndSwapInt32(&ended, 0, 1) {
+			return
+		}
+		dur := now().Sub(start)
+		var failure error
+		for _, err := range errs {
+			if err != nil {
+				failure = err
+				break
+			}
+		}
+		recordTiming(format, dur, failure != nil)
+		if o == nil {
+			return
+		}
+		if failure != nil {
+			o.outputf(false, 0, outputOpts{}, "%s failed (%s): %s\n", name, dur.Round(time.Millisecond), failure)
+		} else {
+			o.outputf(false, 0, outputOpts{}, "%s done (%s)\n", name, dur.Round(time.Millisecond))
+		}
+	}
+}
+
+// Timings returns the aggregated durations of the timed spans that ended so
+// far, sorted by name, see Timed()
+func Timings() []TimingStats {
+	timingMu.Lock()
+	defer timingMu.Unlock()
+	stats := make([]TimingStats, 0, len(timings))
+	for _, ts := range timings {
+		stats = append(stats, *ts)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+// ResetTimings drops the aggregated durations of the timed spans
+func ResetTimings() {
+	timingMu.Lock()
+	{
+		timings = make(map[string]*TimingStats)
+	}
+	timingMu.Unlock()
+}
+
+// DumpTimings outputs a summary of the timed spans at the given level as a
+// table (see Table()) with a row per span name, nothing is output if no
+// spans ended.  To get the summary when the tool exits via the 'out' package
+// use a defer func, eg:
+//
+//	out.SetDeferFunc(func(int) { out.DumpTimings(out.LevelVerbose) })
+func DumpTimings(level Level) {
+	o := levelOutputter(level)
+	stats := Timings()
+	if o == nil || len(stats) == 0 {
+		return
+	}
+	var rows [][]string
+	for _, ts := range stats {
+		rows = append(rows, []string{
+			ts.Name,
+			strconv.Itoa(ts.Count),
+			strconv.Itoa(ts.Failures),
+			ts.Total.Round(time.Millisecond).String(),
+			ts.Avg().Round(time.Millisecond).String(),
+			ts.Min.Round(time.Millisecond).String(),
+			ts.Max.Round(time.Millisecond).String(),
+		})
+	}
+	o.tableOutput([]string{"SPAN", "COUNT", "FAILED", "TOTAL", "AVG", "MIN", "MAX"}, rows)
+}
+
+// recordTiming adds the duration of an ended span to the aggregates of the
+// spans with the same name
+func recordTiming(name string, dur time.Duration, failed bool) {
+	timingMu.Lock()
+	defer timingMu.Unlock()
+	ts, ok := timings[name]
+	if !ok {
+		ts = &TimingStats{Name: name, Min: dur, Max: dur}
+		timings[name] = ts
+	}
+	ts.Count++
+	ts.Total += dur
+	if failed {
+		ts.Failures++
+	}
+	if dur < ts.Min {
+		ts.Min = dur
+	}
+	if dur > ts.Max {
+		ts.Max = dur
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package test for: out/timed.go
+//   Testing in this file focuses on timed spans, marking failed spans and the
+//   aggregated timings and their summary
+
+package out
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dvln/testify/assert"
+)
+
+func TestTimed(t *testing.T) {
+	screenBuf := new(bytes.Buffer)
+	logBuf := new(bytes.Buffer)
+	SetWriter(LevelAll, screenBuf, ForScreen)
+	SetWriter(LevelAll, logBuf, ForLogfile)
+	SetThreshold(LevelVerbose, ForScreen)
+	SetThreshold(LevelVerbose, ForLogfile)
+	SetFlags(LevelAll, Lshortfile, ForLogfile)
+	clk := &fakeClock{t: time.Date(2016, 3, 1, 12, 0, 0, 0, time.UTC)}
+	SetClock(clk.now)
+	ResetTimings()
+
+	done := Timed(LevelVerbose, "sync repo %s", "dvln")
+	clk.t = clk.t.Add(1500 * time.Millisecond)
+	done()
+	done(errors.New("ignored, already ended"))
+	done = Timed(LevelVerbose, "sync repo %s", "out")
+	clk.t = clk.t.Add(500 * time.Millisecond)
+	done(nil, errors.New("no such host"))
+	assert.Equal(t, "sync repo dvln started\n"+
+		"sync repo dvln done (1.5s)\n"+
+		"sync repo out started\n"+
+		"sync repo out failed (500ms): no such host\n", screenBuf.String())
+	// the metadata points at the caller for the start and the end
+	assert.Equal(t, 4, bytes.Count(logBuf.Bytes(), []byte("timed_test.go:")))
+
+	// spans are aggregated by format string, even if not output
+	Timed(LevelDebug, "build")()
+	stats := Timings()
+	assert.Equal(t, 2, len(stats))
+	assert.Equal(t, TimingStats{Name: "build", Count: 1}, stats[0])
+	assert.Equal(t, TimingStats{Name: "sync repo %s", Count: 2, Failures: 1,
+		Total: 2 * time.Second, Min: 500 * time.Millisecond, Max: 1500 * time.Millisecond}, stats[1])
+	assert.Equal(t, time.Second, stats[1].Avg())
+
+	screenBuf.Reset()
+	DumpTimings(LevelInfo)
+	assert.Equal(t, "SPAN          COUNT  FAILED  TOTAL  AVG  MIN    MAX\n"+
+		"----          -----  ------  -----  ---  ---    ---\n"+
+		"build         1      0       0s     0s   0s     0s\n"+
+		"sync repo %s  2      1       2s     1s   500ms  1.5s\n", screenBuf.String())
+
+	// nothing to dump once reset
+	screenBuf.Reset()
+	ResetTimings()
+	DumpTimings(LevelInfo)
+	assert.Equal(t, "", screenBuf.String())
+	ResetOutPkg()
+}
@@ -0,0 +1,117 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package out
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// traceIndent is the indentation of each level of traced call depth
+const traceIndent = "  "
+
+var (
+	// traceMu protects the traced call depth of each goroutine
+	traceMu sync.Mutex
+
+	// traceDepths holds the number of traced calls each goroutine is in
+	traceDepths = make(map[uint64]int)
+
+	// traceNoop is what Enter() returns if the call isn't traced
+	traceNoop = func() {}
+)
+
+// Enter traces entry to and exit from the calling function at the Trace
+// level, call the returned func when the function returns, eg:
+//
+//	func (r *Repo) Sync(name string, depth int) error {
+//		defer out.Enter(name, depth)()
+//		...
+//	}
+//
+//	Trace: -> repo.(*Repo).Sync(dvln, 2)
+//	Trace:   -> repo.fetch(dvln)
+//	Trace:   <- repo.fetch (11.873ms)
+//	Trace: <- repo.(*Repo).Sync (12.104ms)
+//
+// The args (if any) are shown as with fmt.Sprint() and calls traced within
+// the function are indented (per goroutine).  If a debug scope is set (see
+// SetDebugScope()) only functions within the scope are traced.  If Trace
+// output isn't going to the screen or log file nothing is done besides a
+// quick threshold check, so it's cheap to leave in place.
+func Enter(args ...interface{}) func() {
+	if !traceWanted() {
+		return traceNoop
+	}
+	pc, _, _, ok := runtime.Caller(1)
+	if !ok {
+		return traceNoop
+	}
+	funcName := "???"
+	if f := runtime.FuncForPC(pc); f != nil {
+		funcName = f.Name()
+	}
+	if !inDebugScope(funcName) {
+		return traceNoop
+	}
+	funcName = funcName[strings.LastIndex(funcName, "/")+1:]
+	strArgs := make([]string, len(args))
+	for i, arg := range args {
+		strArgs[i] = fmt.Sprint(arg)
+	}
+	gid := goroutineID()
+	traceMu.Lock()
+	depth := traceDepths[gid]
+	traceDepths[gid] = depth + 1
+	traceMu.Unlock()
+	indent := strings.Repeat(traceIndent, depth)
+	TRACE.outputf(false, 0, outputOpts{}, "%s-> %s(%s)\n", indent, funcName, strings.Join(strArgs, ", "))
+	start := now()
+	return func() {
+		traceMu.Lock()
+		if traceDepths[gid] <= 1 {
+			delete(traceDepths, gid)
+		} else {
+			traceDepths[gid]--
+		}
+		traceMu.Unlock()
+		TRACE.outputf(false, 0, outputOpts{}, "%s<- %s (%s)\n", indent, funcName, now().Sub(start).Round(time.Microsecond))
+	}
+}
+
+// traceWanted returns true if Trace level output goes to the screen or the
+// log file
+func traceWanted() bool {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return screenWanted(LevelTrace, screenThreshold, quiet, silent, false) || LevelTrace >= logThreshold
+}
+
+// inDebugScope returns true if the function (eg: "github.com/dvln/out.Enter")
+// is within the debug scope, see SetDebugScope()
+func inDebugScope(funcName string) bool {
+	scope := currentDebugScope()
+	if scope == "" {
+		return true
+	}
+	for _, scopePart := range strings.Split(scope, ",") {
+		if strings.Contains(funcName, scopePart) {
+			return true
+		}
+	}
+	return false
+}
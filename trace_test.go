@@ -0,0 +1,80 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package test for: out/trace.go
+//   Testing in this file focuses on function entry/exit tracing, the call
+//   depth indentation and respecting the debug scope and trace threshold
+
+package out
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dvln/testify/assert"
+)
+
+// traceOuter and traceInner are traced funcs for the tests
+func traceOuter(clk *fakeClock, name string, n int) {
+	defer Enter(name, n)()
+	clk.t = clk.t.Add(2 * time.Millisecond)
+	traceInner(clk)
+}
+
+func traceInner(clk *fakeClock) {
+	defer Enter()()
+	clk.t = clk.t.Add(10 * time.Millisecond)
+}
+
+func TestEnter(t *testing.T) {
+	screenBuf := new(bytes.Buffer)
+	logBuf := new(bytes.Buffer)
+	SetWriter(LevelAll, screenBuf, ForScreen)
+	SetWriter(LevelAll, logBuf, ForLogfile)
+	SetFlags(LevelAll, 0, ForScreen)
+	SetFlags(LevelAll, Lshortfile|Lshortfunc, ForLogfile)
+	envScope := os.Getenv("PKG_OUT_DEBUG_SCOPE")
+	defer os.Setenv("PKG_OUT_DEBUG_SCOPE", envScope)
+	os.Unsetenv("PKG_OUT_DEBUG_SCOPE")
+	clk := &fakeClock{t: time.Date(2016, 3, 1, 12, 0, 0, 0, time.UTC)}
+	SetClock(clk.now)
+
+	// nothing traced unless trace output goes somewhere
+	traceOuter(clk, "dvln", 2)
+	assert.Equal(t, "", screenBuf.String())
+	assert.Equal(t, "", logBuf.String())
+
+	SetThreshold(LevelTrace, ForScreen)
+	SetThreshold(LevelTrace, ForLogfile)
+	traceOuter(clk, "dvln", 2)
+	assert.Equal(t, "Trace: -> out.traceOuter(dvln, 2)\n"+
+		"Trace:   -> out.traceInner()\n"+
+		"Trace:   <- out.traceInner (10ms)\n"+
+		"Trace: <- out.traceOuter (12ms)\n", screenBuf.String())
+	// the metadata is for the traced func, on entry and exit
+	assert.Equal(t, 2, bytes.Count(logBuf.Bytes(), []byte(":traceOuter")))
+	assert.Equal(t, 2, bytes.Count(logBuf.Bytes(), []byte(":traceInner")))
+	traceMu.Lock()
+	assert.Equal(t, 0, len(traceDepths))
+	traceMu.Unlock()
+
+	// only funcs in the debug scope are traced (and indented)
+	screenBuf.Reset()
+	SetDebugScope("out.traceInner")
+	traceOuter(clk, "dvln", 2)
+	assert.Equal(t, "Trace: -> out.traceInner()\n"+
+		"Trace: <- out.traceInner (10ms)\n", screenBuf.String())
+	ResetOutPkg()
+}
@@ -0,0 +1,33 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+
+package out
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// terminalWidth can't query the terminal size on this platform, the COLUMNS
+// env setting (or SetWrapWidth()) is used instead
+func terminalWidth(w io.Writer) int {
+	return 0
+}
+
+// setEcho can't turn off the echo of typed in characters on this platform
+func setEcho(file *os.File, on bool) error {
+	return errors.New("turning off the terminal echo isn't supported on this platform")
+}
@@ -0,0 +1,62 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package out
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// winsize is the terminal size as returned by the TIOCGWINSZ ioctl
+type winsize struct {
+	rows, cols, xpixel, ypixel uint16
+}
+
+// terminalWidth returns the number of columns of the terminal the writer
+// is, 0 if it isn't a terminal (or the size can't be queried)
+func terminalWidth(w io.Writer) int {
+	file, ok := w.(*os.File)
+	if !ok {
+		return 0
+	}
+	var ws winsize
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&ws)))
+	if errno != 0 {
+		return 0
+	}
+	return int(ws.cols)
+}
+
+// setEcho turns the echo of typed in characters on or off for the terminal
+func setEcho(file *os.File, on bool) error {
+	var termios syscall.Termios
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), ioctlGetTermios, uintptr(unsafe.Pointer(&termios)))
+	if errno != 0 {
+		return errno
+	}
+	if on {
+		termios.Lflag |= syscall.ECHO
+	} else {
+		termios.Lflag &^= syscall.ECHO
+	}
+	_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), ioctlSetTermios, uintptr(unsafe.Pointer(&termios)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package out
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConfigWatcher reloads an 'out' config file (see LoadConfig()) when the
+// process gets a SIGHUP or when the file changes, see WatchConfig()
+type ConfigWatcher struct {
+	path     string
+	interval time.Duration
+	mu       sync.Mutex // serializes reloads
+	base     *State     // the settings the config is applied on top of
+	modTime  time.Time
+	size     int64
+	sighup   chan os.Signal
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// WatchConfig loads the given 'out' config file and then watches it, the
+// config is reloaded on SIGHUP (on platforms that have it) or, if the
+// interval is > 0, when the file's modification time (or size) changes as
+// checked at that interval, eg:
+//
+//	watcher, err := out.WatchConfig("/etc/mydaemon/out.json", 5*time.Second)
+//	if err != nil {
+//		out.Fatalln(err)
+//	}
+//	defer watcher.Stop()
+//
+// See Reload() for what a reload does.  An error is returned (and nothing is
+// watched) if the initial load fails.
+func WatchConfig(path string, interval time.Duration) (*ConfigWatcher, error) {
+	w := &ConfigWatcher{
+		path:     expandHome(path),
+		interval: interval,
+		base:     Snapshot(),
+		sighup:   make(chan os.Signal, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	w.modTime, w.size = w.stat()
+	if err := loadConfigFile(w.path, w.base); err != nil {
+		return nil, err
+	}
+	notifySighup(w.sighup)
+	go w.watch()
+	return w, nil
+}
+
+// Stop stops watching the config file (the current settings are kept)
+func (w *ConfigWatcher) Stop() {
+	w.stopOnce.Do(func() {
+		signal.Stop(w.sighup)
+		close(w.stop)
+	})
+	<-w.done
+}
+
+// Reload re-reads the config file and applies it as a whole on top of the
+// settings 'out' had when watching started, ie: settings removed from the
+// config go back to what they were then (as do settings changed since then
+// by other means).  If the new config is invalid an Issue is logged and the
+// current config is kept.  On success the log file is reopened (so external
+// log rotation works, unless the config itself opened a new log file) and a
+// Note describing the changed settings is logged.  Any error is also returned.
+func (w *ConfigWatcher) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.modTime, w.size = w.stat()
+	before := Snapshot()
+	if err := loadConfigFile(w.path, w.base); err != nil {
+		Issuef("Failed to reload config %s, keeping current config:\n%s\n", w.path, err)
+		return err
+	}
+	after := Snapshot()
+	var err error
+	if oldWr := logfileWriter(before); oldWr == logfileWriter(after) {
+		err = ReopenLogFile()
+		// the starting settings have to follow the reopened log file
+		w.base.replaceLogfile(oldWr, logfileWriter(Snapshot()))
+	} else {
+		closeUnusedLogfile(before, after, w.base)
+	}
+	changes := stateChanges(before, after)
+	if changes == nil {
+		Notef("Reloaded config %s (no changes)\n", w.path)
+	} else {
+		Notef("Reloaded config %s:\n  %s\n", w.path, strings.Join(changes, "\n  "))
+	}
+	if err != nil {
+		Issueln("Failed to reopen log file:", err)
+	}
+	return err
+}
+
+// watch waits for a SIGHUP or a change of the config file until stopped
+func (w *ConfigWatcher) watch() {
+	defer close(w.done)
+	var tick <-chan time.Time
+	if w.interval > 0 {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-w.sighup:
+			w.Reload()
+		case <-tick:
+			w.mu.Lock()
+			modTime, size := w.stat()
+			changed := !modTime.Equal(w.modTime) || size != w.size
+			w.mu.Unlock()
+			if changed {
+				w.Reload()
+			}
+		}
+	}
+}
+
+// stat returns the modification time and size of the config file, zero
+// values if it can't be read (eg: in the middle of being replaced)
+func (w *ConfigWatcher) stat() (time.Time, int64) {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return time.Time{}, 0
+	}
+	return info.ModTime(), info.Size()
+}
+
+// logfileWriter returns the writer of the State's log file (see LogFileName())
+// if any level is writing to it, else nil
+func logfileWriter(s *State) io.Writer {
+	for _, l := range s.levels {
+		switch wr := l.logfileHndl.(type) {
+		case *os.File:
+			if s.logFileName != "" && wr.Name() == s.logFileName {
+				return wr
+			}
+		case *RotateWriter:
+			if s.logFileName != "" && wr.filename == s.logFileName {
+				return wr
+			}
+		}
+	}
+	return nil
+}
+
+// replaceLogfile points the levels of the State that write to the old log
+// file writer at the new one
+func (s *State) replaceLogfile(oldWr, newWr io.Writer) {
+	if oldWr == nil || newWr == nil || oldWr == newWr {
+		return
+	}
+	for i := range s.levels {
+		if s.levels[i].logfileHndl == oldWr {
+			s.levels[i].logfileHndl = newWr
+		}
+	}
+}
+
+// closeUnusedLogfile closes the log file 'out' had open before a config was
+// applied if neither the new settings nor the starting settings (which a
+// reload goes back to) write to it
+func closeUnusedLogfile(before, after, base *State) {
+	old := logfileWriter(before)
+	if old == nil {
+		return
+	}
+	for _, l := range append(after.levels, base.levels...) {
+		if l.logfileHndl == old {
+			return
+		}
+	}
+	closeLogfile(old)
+}
+
+// stateChanges describes the settings that differ between the two States,
+// one "<setting>: <old> -> <new>" entry per setting (nil if none differ),
+// the settings are named as in the JSON form of a State (see MarshalJSON())
+func stateChanges(before, after *State) []string {
+	beforeVals := flattenState(before)
+	afterVals := flattenState(after)
+	var changes []string
+	for key, val := range afterVals {
+		beforeVal, ok := beforeVals[key]
+		if !ok {
+			beforeVal = `""`
+		}
+		if beforeVal != val {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", key, beforeVal, val))
+		}
+	}
+	for key, val := range beforeVals {
+		if _, ok := afterVals[key]; !ok {
+			changes = append(changes, fmt.Sprintf(`%s: %s -> ""`, key, val))
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+// flattenState maps the dotted names of the State settings (as in its JSON
+// form, eg: "levels.NOTE.prefix") to their JSON encoded values, the newline
+// tracking is left out as it isn't a setting
+func flattenState(s *State) map[string]string {
+	vals := make(map[string]string)
+	stateJSON, err := json.Marshal(s)
+	if err != nil {
+		return vals
+	}
+	var tree map[string]interface{}
+	if json.Unmarshal(stateJSON, &tree) != nil {
+		return vals
+	}
+	delete(tree, "screenNewline")
+	delete(tree, "logfileNewline")
+	var flatten func(prefix string, val interface{})
+	flatten = func(prefix string, val interface{}) {
+		if m, ok := val.(map[string]interface{}); ok {
+			for key, subVal := range m {
+				flatten(prefix+key+".", subVal)
+			}
+			return
+		}
+		var valJSON bytes.Buffer
+		enc := json.NewEncoder(&valJSON)
+		enc.SetEscapeHTML(false)
+		enc.Encode(val)
+		vals[strings.TrimSuffix(prefix, ".")] = strings.TrimSpace(valJSON.String())
+	}
+	flatten("", tree)
+	return vals
+}
@@ -0,0 +1,176 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package test for: out/watch.go
+//   Testing in this file focuses on reloading a config file when it changes
+//   or on SIGHUP, including reopening the log file after it has been moved
+
+package out
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dvln/testify/assert"
+)
+
+// syncBuffer is a bytes.Buffer that can be read while the watcher writes
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// waitFor polls the condition for up to a couple of seconds
+func waitFor(cond func() bool) bool {
+	for i := 0; i < 200; i++ {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestWatchConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "outwatch")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	cfgPath := filepath.Join(dir, "out.json")
+	logPath := filepath.Join(dir, "tool.log")
+	screenBuf := &syncBuffer{}
+	SetWriter(LevelAll, screenBuf, ForScreen)
+
+	writeCfg := func(cfg string, age time.Duration) {
+		assert.Equal(t, nil, ioutil.WriteFile(cfgPath, []byte(cfg), 0666))
+		mtime := time.Now().Add(-age)
+		os.Chtimes(cfgPath, mtime, mtime)
+	}
+	writeCfg(`{ "logfile": { "threshold": "info", "path": "`+logPath+`", "flags": "off" } }`, time.Hour)
+	watcher, err := WatchConfig(cfgPath, 10*time.Millisecond)
+	assert.Equal(t, nil, err)
+	if watcher == nil {
+		ResetOutPkg()
+		return
+	}
+	assert.Equal(t, LevelInfo, Threshold(ForLogfile))
+
+	// a changed file is picked up and the changes are noted
+	writeCfg(`{ "screen": { "threshold": "debug" }, "logfile": { "threshold": "info", "path": "`+logPath+`", "flags": "off" } }`, 0)
+	assert.Equal(t, true, waitFor(func() bool { return Threshold(ForScreen) == LevelDebug }))
+	assert.Equal(t, true, waitFor(func() bool {
+		return strings.Contains(screenBuf.String(), `screenThreshold: "INFO" -> "DEBUG"`)
+	}))
+
+	// an invalid config is reported and the current config kept
+	writeCfg(`{ "screen": { "threshold": "loud" } }`, 2*time.Hour)
+	err = watcher.Reload()
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, LevelDebug, Threshold(ForScreen))
+	assert.Contains(t, screenBuf.String(), "keeping current config:\n")
+	assert.Contains(t, screenBuf.String(), `screen.threshold: unknown level "loud"`)
+
+	// settings removed from the config go back to what they were before the
+	// config was first loaded
+	writeCfg(`{ "logfile": { "threshold": "info", "path": "`+logPath+`", "flags": "off" } }`, 3*time.Hour)
+	assert.Equal(t, nil, watcher.Reload())
+	assert.Equal(t, LevelInfo, Threshold(ForScreen))
+	assert.Contains(t, screenBuf.String(), `screenThreshold: "DEBUG" -> "INFO"`)
+
+	// SIGHUP reopens the log file after it's been moved away (eg: logrotate)
+	Infoln("before rotation")
+	assert.Equal(t, nil, os.Rename(logPath, logPath+".1"))
+	if !raiseSighup() {
+		assert.Equal(t, nil, watcher.Reload())
+	}
+	assert.Equal(t, true, waitFor(func() bool {
+		_, err := os.Stat(logPath)
+		return err == nil
+	}))
+	watcher.Stop()
+	Infoln("after rotation")
+	ResetOutPkg()
+
+	logged, _ := ioutil.ReadFile(logPath + ".1")
+	assert.Contains(t, string(logged), `Note:   screenThreshold: "INFO" -> "DEBUG"`)
+	assert.Equal(t, true, strings.HasSuffix(string(logged), "\nbefore rotation\n"))
+	logged, _ = ioutil.ReadFile(logPath)
+	assert.Contains(t, string(logged), "(no changes)\n")
+	assert.NotContains(t, string(logged), "before rotation")
+	assert.Equal(t, true, strings.HasSuffix(string(logged), "\nafter rotation\n"))
+}
+
+func TestWatchConfigKeepsLogFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "outwatch")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	cfgPath := filepath.Join(dir, "out.json")
+	logPath := filepath.Join(dir, "tool.log")
+	SetWriter(LevelAll, ioutil.Discard, ForScreen)
+	SetLogFile(logPath)
+	SetThreshold(LevelInfo, ForLogfile)
+	SetFlags(LevelAll, 0, ForLogfile)
+
+	// the log file isn't in the config, reloads reopen the one set up before
+	assert.Equal(t, nil, ioutil.WriteFile(cfgPath, []byte(`{ "screen": { "threshold": "debug" } }`), 0666))
+	watcher, err := WatchConfig(cfgPath, 0)
+	assert.Equal(t, nil, err)
+	if watcher == nil {
+		ResetOutPkg()
+		return
+	}
+	for i := 1; i <= 2; i++ {
+		Infoln("rotation", i)
+		assert.Equal(t, nil, os.Rename(logPath, fmt.Sprintf("%s.%d", logPath, i)))
+		assert.Equal(t, nil, watcher.Reload())
+	}
+	watcher.Stop()
+	Infoln("done")
+	ResetOutPkg()
+
+	logged, _ := ioutil.ReadFile(logPath + ".2")
+	assert.Equal(t, true, strings.HasSuffix(string(logged), "\nrotation 2\n"))
+	logged, _ = ioutil.ReadFile(logPath)
+	assert.Equal(t, true, strings.HasSuffix(string(logged), "\ndone\n"))
+}
+
+func TestStateChanges(t *testing.T) {
+	before := Snapshot()
+	SetThreshold(LevelVerbose, ForScreen)
+	SetPrefix(LevelNote, "NOTE> ")
+	after := Snapshot()
+	ResetOutPkg()
+
+	changes := stateChanges(before, after)
+	assert.Equal(t, 2, len(changes))
+	assert.Equal(t, `levels.NOTE.prefix: "Note: " -> "NOTE> "`+"\n"+`screenThreshold: "INFO" -> "VERBOSE"`, strings.Join(changes, "\n"))
+	assert.Equal(t, 0, len(stateChanges(before, before)))
+}
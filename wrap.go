@@ -0,0 +1,260 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package out
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// minWrapWidth is the narrowest text column worth wrapping into, if the
+// prefix and metadata leave less room than this the line is left alone
+const minWrapWidth = 20
+
+// wrapWidth is the screen width to wrap at, 0 means the terminal width
+var wrapWidth int
+
+// Wrap returns true if screen output for the given level is soft wrapped,
+// see SetWrap()
+func Wrap(level Level) bool {
+	level = levelCheck(level)
+	var wrap bool
+	for _, o := range outputters {
+		o.mu.RLock()
+		if o.level == level {
+			wrap = o.wrap
+		}
+		o.mu.RUnlock()
+	}
+	return wrap
+}
+
+// SetWrap turns soft wrapping of screen output on or off for the given level
+// (or LevelAll), it is off by default.  Long lines are broken at spaces (or
+// mid-word if a word doesn't fit) so they fit the screen width, see
+// SetWrapWidth(), and the continuation lines are indented with blanks to
+// line up under the text after the prefix and any flag metadata, eg:
+//
+//	Note: The frobnicator could not reach the upstream server, retrying
+//	      in 5 seconds (attempt 2 of 3)
+//
+// Only output to a terminal is wrapped (unless a width is set), log file
+// output is never wrapped.
+func SetWrap(level Level, wrap bool) {
+	for _, o := range outputters {
+		o.mu.Lock()
+		if level == LevelAll || o.level == level {
+			o.wrap = wrap
+		}
+		o.mu.Unlock()
+	}
+}
+
+// WrapWidth returns the screen width set via SetWrapWidth() (0 if the
+// terminal width is used)
+func WrapWidth() int {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return wrapWidth
+}
+
+// SetWrapWidth sets the width that screen output is wrapped at for levels
+// that have wrapping turned on (see SetWrap()) and that tables are fit to
+// (see Table()), a width is used for any screen writer (eg: a buffer or
+// pipe).  The default of 0 uses the width of
+// the terminal (if the screen writer is one), falling back to the COLUMNS
+// env setting if the width can't be queried.
+func SetWrapWidth(width int) {
+	if width < 0 {
+		width = 0
+	}
+	mutex.Lock()
+	{
+		wrapWidth = width
+	}
+	mutex.Unlock()
+}
+
+// screenWrapWidth returns the width to wrap screen output for this level
+// at, 0 if it isn't wrapped
+func (o *LvlOutput) screenWrapWidth() int {
+	o.mu.RLock()
+	wrap := o.wrap
+	w := o.screenHndl
+	o.mu.RUnlock()
+	if !wrap {
+		return 0
+	}
+	return screenWidth(w)
+}
+
+// screenWidth returns the width of the screen the writer is, ie: the width
+// set via SetWrapWidth() or, for a terminal, its width (or the COLUMNS env
+// setting if it can't be queried), 0 if unknown
+func screenWidth(w io.Writer) int {
+	if width := WrapWidth(); width > 0 {
+		return width
+	}
+	if !isTerminal(w) {
+		return 0
+	}
+	if width := terminalWidth(w); width > 0 {
+		return width
+	}
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+	return 0
+}
+
+// wrapPrefixed soft wraps the lines of a prefixed string (ie: after the
+// level prefix and flag metadata have been added to the lines of orig) to
+// the given width, the continuation lines are blank indented to line up
+// under the original text, lines that fit (or weren't prefixed as expected)
+// are left as they are
+func wrapPrefixed(s string, orig string, width int) string {
+	lines := strings.Split(s, "\n")
+	origLines := strings.Split(orig, "\n")
+	if len(lines) != len(origLines) {
+		return s
+	}
+	for idx, line := range lines {
+		if displayWidth(line) <= width || !strings.HasSuffix(line, origLines[idx]) {
+			continue
+		}
+		leader := line[:len(line)-len(origLines[idx])]
+		indent := displayWidth(leader)
+		if width-indent < minWrapWidth {
+			continue
+		}
+		pieces := wrapText(origLines[idx], width-indent)
+		blank := strings.Repeat(" ", indent)
+		lines[idx] = leader + strings.Join(pieces, "\n"+blank)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// wrapText breaks a single line of text into pieces no wider than the given
+// width, breaking at spaces where possible and mid-word otherwise, leading
+// spaces (indentation) are kept and the spaces at a break are dropped
+func wrapText(s string, width int) []string {
+	var pieces []string
+	line := ""
+	lineWidth := 0
+	for idx, word := range strings.Split(s, " ") {
+		wordWidth := displayWidth(word)
+		sep := 1
+		if idx == 0 {
+			sep = 0
+		} else if lineWidth == 0 && pieces != nil {
+			// start of a continuation line, drop any extra spaces
+			if word == "" {
+				continue
+			}
+			sep = 0
+		}
+		if lineWidth+sep+wordWidth <= width {
+			line += strings.Repeat(" ", sep) + word
+			lineWidth += sep + wordWidth
+			continue
+		}
+		if lineWidth > 0 {
+			pieces = append(pieces, strings.TrimRight(line, " "))
+		}
+		for wordWidth > width {
+			var head string
+			head, word = splitAtWidth(word, width)
+			pieces = append(pieces, head)
+			wordWidth = displayWidth(word)
+		}
+		line, lineWidth = word, wordWidth
+	}
+	return append(pieces, line)
+}
+
+// splitAtWidth splits the string at the given display width, any ANSI escape
+// sequences stay with the head, at least one rune goes into the head
+func splitAtWidth(s string, width int) (string, string) {
+	used := 0
+	for i := 0; i < len(s); {
+		if n := ansiLen(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		rw := runeWidth(r)
+		if used+rw > width && used > 0 {
+			return s[:i], s[i:]
+		}
+		used += rw
+		i += size
+	}
+	return s, ""
+}
+
+// ansiLen returns the length of the ANSI escape sequence at the start of the
+// string, 0 if there isn't one
+func ansiLen(s string) int {
+	if len(s) < 2 || s[0] != 0x1b || s[1] != '[' {
+		return 0
+	}
+	i := 2
+	for i < len(s) && (s[i] < 0x40 || s[i] > 0x7e) {
+		i++
+	}
+	if i < len(s) {
+		i++ // the final byte
+	}
+	return i
+}
+
+// displayWidth returns the number of columns the string takes up on the
+// screen, any ANSI escape sequences take up none, East Asian wide runes
+// take up two and combining marks take up none
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range stripANSI(s) {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// wideRanges are the East Asian wide and fullwidth rune ranges (roughly,
+// the CJK, Hangul, fullwidth forms and emoji blocks)
+var wideRanges = [][2]rune{
+	{0x1100, 0x115f}, {0x2e80, 0x303e}, {0x3041, 0x33ff}, {0x3400, 0x4dbf},
+	{0x4e00, 0x9fff}, {0xa000, 0xa4cf}, {0xac00, 0xd7a3}, {0xf900, 0xfaff},
+	{0xfe30, 0xfe4f}, {0xff00, 0xff60}, {0xffe0, 0xffe6}, {0x1f300, 0x1f64f},
+	{0x1f900, 0x1f9ff}, {0x20000, 0x2fffd}, {0x30000, 0x3fffd},
+}
+
+// runeWidth returns the number of screen columns the rune takes up
+func runeWidth(r rune) int {
+	switch {
+	case r == 0, unicode.Is(unicode.Mn, r), unicode.Is(unicode.Me, r), unicode.Is(unicode.Cf, r):
+		return 0
+	case r < 0x1100:
+		return 1
+	}
+	for _, wide := range wideRanges {
+		if r >= wide[0] && r <= wide[1] {
+			return 2
+		}
+	}
+	return 1
+}
@@ -0,0 +1,105 @@
+// Copyright © 2016 Erik Brady <brady@dvln.org>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package test for: out/wrap.go
+//   Testing in this file focuses on soft wrapping screen output to the screen
+//   width and the display width of Unicode and colored text
+
+package out
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/dvln/testify/assert"
+)
+
+func TestDisplayWidth(t *testing.T) {
+	assert.Equal(t, 5, displayWidth("hello"))
+	assert.Equal(t, 4, displayWidth("日本"))
+	assert.Equal(t, 4, displayWidth("한국"))
+	assert.Equal(t, 4, displayWidth("café")) // combining accent
+	assert.Equal(t, 6, displayWidth("\x1b[31mError:\x1b[0m"))
+	assert.Equal(t, 2, displayWidth("🎉"))
+}
+
+func TestWrapText(t *testing.T) {
+	assert.Equal(t, []string{"the quick", "brown fox", "jumps"}, wrapText("the quick brown fox jumps", 10))
+	assert.Equal(t, []string{"short"}, wrapText("short", 10))
+	assert.Equal(t, []string{"abcdefghij", "klm nop"}, wrapText("abcdefghijklm nop", 10))
+	assert.Equal(t, []string{"  indented", "text"}, wrapText("  indented text", 10))
+	assert.Equal(t, []string{"日本語の", "テキスト"}, wrapText("日本語のテキスト", 8))
+	assert.Equal(t, []string{"a", "b"}, wrapText("a     b", 3))
+}
+
+func TestWrapOutput(t *testing.T) {
+	screenBuf := new(bytes.Buffer)
+	logBuf := new(bytes.Buffer)
+	SetWriter(LevelAll, screenBuf, ForScreen)
+	SetWriter(LevelAll, logBuf, ForLogfile)
+	SetThreshold(LevelInfo, ForLogfile)
+	SetFlags(LevelAll, 0, ForLogfile)
+	msg := "The frobnicator could not reach the upstream server, retrying in 5 seconds"
+
+	// off by default, and a buffer has no terminal width to wrap to
+	Noteln(msg)
+	SetWrap(LevelNote, true)
+	Noteln(msg)
+	assert.Equal(t, strings.Repeat("Note: "+msg+"\n", 2), screenBuf.String())
+
+	screenBuf.Reset()
+	logBuf.Reset()
+	SetWrapWidth(40)
+	assert.Equal(t, true, Wrap(LevelNote))
+	assert.Equal(t, false, Wrap(LevelIssue))
+	Noteln(msg)
+	assert.Equal(t, "Note: The frobnicator could not reach\n"+
+		"      the upstream server, retrying in 5\n"+
+		"      seconds\n", screenBuf.String())
+	assert.Equal(t, "Note: "+msg+"\n", logBuf.String())
+
+	// continuation lines line up after the flag metadata too
+	screenBuf.Reset()
+	SetFlags(LevelNote, Llevel, ForScreen)
+	Noteln(msg)
+	assert.Equal(t, "NOTE    Note: The frobnicator could not\n"+
+		"              reach the upstream server,\n"+
+		"              retrying in 5 seconds\n", screenBuf.String())
+
+	// and after a colored prefix
+	screenBuf.Reset()
+	SetFlags(LevelNote, 0, ForScreen)
+	SetColorConfig(ColorAlways)
+	Noteln(msg)
+	assert.Equal(t, "\x1b[32mNote:\x1b[0m The frobnicator could not reach\n"+
+		"      the upstream server, retrying in 5\n"+
+		"      seconds\n", screenBuf.String())
+	ResetOutPkg()
+
+	// config file settings
+	err := LoadConfig(strings.NewReader(`{"screen": {"wrapWidth": 30}, "levels": {"all": {"wrap": true}}}`))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 30, WrapWidth())
+	assert.Equal(t, true, Wrap(LevelVerbose))
+	err = LoadConfig(strings.NewReader(`{"logfile": {"wrapWidth": 30}}`))
+	assert.NotEqual(t, nil, err)
+	ResetOutPkg()
+	assert.Equal(t, 0, WrapWidth())
+	assert.Equal(t, false, Wrap(LevelVerbose))
+}
+
+func TestBlankContinue(t *testing.T) {
+	assert.Equal(t, "Note: one\n      two\n", InsertPrefix("one\ntwo\n", "Note: ", BlankContinue, 0))
+	assert.Equal(t, "one\n      two", InsertPrefix("one\ntwo", "Note: ", BlankContinue|SkipFirstLine, 0))
+}